@@ -1,40 +1,287 @@
 package golib
 
 import (
+	"encoding"
 	"fmt"
 	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// BindQueryParam binding query param from HTTP context to struct model with key in json tag
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindQueryParam binding query param from HTTP context to struct model with key in json tag,
+// query is unescaped as url path so "+" is kept as is (ex: "+07:00" of time zone), use BindQuery(u.Query(), target) to decode "+" to space
 func BindQueryParam(u *url.URL, target interface{}) error {
+	return BindQuery(parsePathQuery(u.RawQuery), target)
+}
+
+// parsePathQuery parse raw query with every key and value unescaped by url.PathUnescape,
+// value that cannot be unescaped is kept as is
+func parsePathQuery(rawQuery string) url.Values {
+	query := make(url.Values)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		key, value := kv[0], ""
+		if len(kv) > 1 {
+			value = kv[1]
+		}
+		if unescaped, err := url.PathUnescape(key); err == nil {
+			key = unescaped
+		}
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		query[key] = append(query[key], value)
+	}
+	return query
+}
+
+// BindQuery bind url query values to struct target (must in pointer) with key in json tag.
+// Supported field types are string, int, uint, float, bool, time.Time (layout in "format" tag, default RFC3339),
+// time.Duration, encoding.TextUnmarshaler, pointer, slice (repeated key or comma separated value)
+// and nested struct (with dotted key, ex: filter.name=value).
+// Value that cannot be converted and failed validate tag rules (see ValidateStruct) will be returned in *MultiError with field key
+func BindQuery(query url.Values, target interface{}) error {
 	refValue := reflect.ValueOf(target)
-	if refValue.Kind() != reflect.Ptr {
+	if refValue.Kind() != reflect.Ptr || refValue.IsNil() {
 		return fmt.Errorf("target is not pointer")
 	}
 	refValue = refValue.Elem()
-	q := make(map[string]string)
-	urlPathUnscape, _ := url.PathUnescape(u.RawQuery)
-	for _, r := range strings.Split(urlPathUnscape, "&") {
-		sp := strings.Split(r, "=")
-		if len(sp) > 1 {
-			q[sp[0]] = sp[1]
-		}
+	if refValue.Kind() != reflect.Struct {
+		return fmt.Errorf("target is not pointer of struct")
 	}
 
+	errs := NewMultiError()
+	bindQueryStruct(query, "", refValue, errs)
+	validateStruct("", refValue, errs)
+	if errs.HasError() {
+		return errs
+	}
+	return nil
+}
+
+func bindQueryStruct(query url.Values, prefix string, refValue reflect.Value, errs *MultiError) {
+	refType := refValue.Type()
 	for i := 0; i < refValue.NumField(); i++ {
 		field := refValue.Field(i)
+		typ := refType.Field(i)
+		if typ.PkgPath != "" && !typ.Anonymous { // unexported field
+			continue
+		}
+
+		key := strings.Split(typ.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+
+		if typ.Anonymous && key == "" { // embedded struct, field promoted to parent
+			if isBindStruct(typ.Type) {
+				if field.Kind() == reflect.Ptr {
+					if !hasQueryPrefix(query, prefix) {
+						continue
+					}
+					if field.IsNil() {
+						field.Set(reflect.New(typ.Type.Elem()))
+					}
+					field = field.Elem()
+				}
+				bindQueryStruct(query, prefix, field, errs)
+			}
+			continue
+		}
+
+		if key == "" {
+			key = typ.Name
+		}
+		key = prefix + key
+
+		if isBindStruct(typ.Type) { // nested struct with dotted key
+			if field.Kind() == reflect.Ptr {
+				if !hasQueryPrefix(query, key+".") {
+					continue
+				}
+				if field.IsNil() {
+					field.Set(reflect.New(typ.Type.Elem()))
+				}
+				field = field.Elem()
+			}
+			bindQueryStruct(query, key+".", field, errs)
+			continue
+		}
 
-		jsonTag := refValue.Type().Field(i).Tag.Get("json")
-		jsonTag = strings.TrimSuffix(jsonTag, ",omitempty")
-		field.SetString(q[jsonTag])
+		var values []string
+		for _, val := range query[key] {
+			if val != "" {
+				values = append(values, val)
+			}
+		}
+		if len(values) == 0 {
+			def, ok := typ.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			values = []string{def}
+		}
+
+		if err := bindQueryValue(field, values, typ.Tag); err != nil {
+			errs.AppendCode(key, ErrorCodeInvalidFormat, err)
+		}
+	}
+}
+
+// isBindStruct check type is struct (or pointer of struct) which must be traversed by field
+func isBindStruct(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return false
+	}
+	return !reflect.PtrTo(typ).Implements(textUnmarshalerType)
+}
+
+func hasQueryPrefix(query url.Values, prefix string) bool {
+	for key := range query {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func bindQueryValue(field reflect.Value, values []string, tag reflect.StructTag) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		val := reflect.New(field.Type().Elem())
+		if err := bindQueryValue(val.Elem(), values, tag); err != nil {
+			return err
+		}
+		field.Set(val)
+		return nil
+
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 { // []byte
+			field.SetBytes([]byte(values[0]))
+			return nil
+		}
+
+		var items []string
+		for _, val := range values {
+			for _, item := range strings.Split(val, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := bindQueryValue(slice.Index(i), []string{item}, tag); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return parseStringToValue(field, values[0], tag)
+}
+
+// parseStringToValue convert string value to target field based on field type
+func parseStringToValue(field reflect.Value, v string, tag reflect.StructTag) error {
+	switch field.Type() {
+	case timeType:
+		layout := tag.Get("format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, v)
+		if err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type time with format '%s'", v, layout)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+
+	case durationType:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type duration", v)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type %s: %v", v, field.Type(), err)
+		}
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		if ok, _ := strconv.ParseBool(tag.Get("lower")); ok {
+			v = strings.ToLower(v)
+		}
+		field.SetString(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		vInt, err := strconv.ParseInt(v, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type number", v)
+		}
+		field.SetInt(vInt)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		vUint, err := strconv.ParseUint(v, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type unsigned number", v)
+		}
+		field.SetUint(vUint)
+	case reflect.Float32, reflect.Float64:
+		vFloat, err := strconv.ParseFloat(v, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type float", v)
+		}
+		field.SetFloat(vFloat)
+	case reflect.Bool:
+		vBool, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("Cannot parse '%s' to type boolean", v)
+		}
+		field.SetBool(vBool)
+	default:
+		return fmt.Errorf("Cannot parse '%s' to unsupported type %s", v, field.Type())
 	}
 	return nil
 }
 
-// ParseFromQueryParam parse url query string to struct target (with multiple data type in struct field), target must in pointer
+// queryParamField cached parse plan of struct field for ParseFromQueryParam and ToQueryParam
+type queryParamField struct {
+	index   []int
+	key     string
+	def     string
+	convert func(v string, field reflect.Value) error
+	encode  func(field reflect.Value) (v string, ok bool)
+	// encoded value of field parsed from default tag, field with this value is omitted by ToQueryParam
+	defEncoded   string
+	defEncodedOK bool
+}
+
+// queryParamPlans cached list of queryParamField by struct type
+var queryParamPlans sync.Map
+
+// ParseFromQueryParam parse url query string to struct target (with multiple data type in struct field), target must in pointer.
+// Field with validate tag will be validated after parsed (see ValidateStruct)
 func ParseFromQueryParam(query url.Values, target interface{}) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -42,8 +289,6 @@ func ParseFromQueryParam(query url.Values, target interface{}) (err error) {
 		}
 	}()
 
-	var parseDataTypeValue func(typ reflect.Type, val reflect.Value)
-
 	var errs = NewMultiError()
 
 	pValue := reflect.ValueOf(target)
@@ -51,60 +296,168 @@ func ParseFromQueryParam(query url.Values, target interface{}) (err error) {
 		panic(fmt.Errorf("%v is not pointer", pValue.Kind()))
 	}
 	pValue = pValue.Elem()
-	pType := reflect.TypeOf(target).Elem()
-	for i := 0; i < pValue.NumField(); i++ {
-		field := pValue.Field(i)
-		typ := pType.Field(i)
-		if typ.Anonymous { // embedded struct
-			err = ParseFromQueryParam(query, field.Addr().Interface())
+	for _, f := range getQueryParamPlan(pValue.Type()) {
+		var v string
+		if val := query[f.key]; len(val) > 0 && val[0] != "" {
+			v = val[0]
+		} else {
+			v = f.def
+		}
+
+		if err := f.convert(v, pValue.FieldByIndex(f.index)); err != nil {
+			errs.AppendCode(f.key, ErrorCodeInvalidFormat, err)
+		}
+	}
+	validateStruct("", pValue, errs)
+
+	if errs.HasError() {
+		return errs
+	}
+
+	return
+}
+
+func getQueryParamPlan(typ reflect.Type) []queryParamField {
+	if plan, ok := queryParamPlans.Load(typ); ok {
+		return plan.([]queryParamField)
+	}
+
+	plan := compileQueryParamPlan(typ, nil)
+	queryParamPlans.Store(typ, plan)
+	return plan
+}
+
+func compileQueryParamPlan(typ reflect.Type, index []int) (plan []queryParamField) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct { // embedded struct
+			plan = append(plan, compileQueryParamPlan(field.Type, fieldIndex)...)
+			continue
+		}
+		if field.PkgPath != "" { // unexported field
+			continue
 		}
 
-		key := strings.TrimSuffix(typ.Tag.Get("json"), ",omitempty")
+		key := strings.TrimSuffix(field.Tag.Get("json"), ",omitempty")
 		if key == "-" {
 			continue
 		}
 
-		var v string
-		if val := query[key]; len(val) > 0 && val[0] != "" {
-			v = val[0]
-		} else {
-			v = typ.Tag.Get("default")
+		lower, _ := strconv.ParseBool(field.Tag.Get("lower"))
+		convert := compileQueryParamConverter(field.Type, lower)
+		if convert == nil {
+			continue
 		}
 
-		parseDataTypeValue = func(tp reflect.Type, targetField reflect.Value) {
-			switch tp.Kind() {
-			case reflect.String:
-				if ok, _ := strconv.ParseBool(typ.Tag.Get("lower")); ok {
-					v = strings.ToLower(v)
-				}
-				targetField.SetString(v)
-			case reflect.Int32, reflect.Int, reflect.Int64:
-				vInt, err := strconv.Atoi(v)
-				if v != "" && err != nil {
-					errs.Append(key, fmt.Errorf("Cannot parse '%s' (%T) to type number", v, v))
-				}
-				targetField.SetInt(int64(vInt))
-			case reflect.Bool:
-				vBool, err := strconv.ParseBool(v)
-				if v != "" && err != nil {
-					errs.Append(key, fmt.Errorf("Cannot parse '%s' (%T) to type boolean", v, v))
-				}
-				targetField.SetBool(vBool)
-			case reflect.Ptr:
-				if v != "" {
-					// allocate new value to pointer targetField
-					targetField.Set(reflect.ValueOf(reflect.New(tp.Elem()).Interface()))
-					parseDataTypeValue(tp.Elem(), targetField.Elem())
-				}
+		f := queryParamField{
+			index: fieldIndex, key: key, def: field.Tag.Get("default"), convert: convert,
+			encode: compileQueryParamEncoder(field.Type),
+		}
+		defValue := reflect.New(field.Type).Elem()
+		f.convert(f.def, defValue)
+		f.defEncoded, f.defEncodedOK = f.encode(defValue)
+		plan = append(plan, f)
+	}
+	return
+}
+
+func compileQueryParamConverter(typ reflect.Type, lower bool) func(v string, field reflect.Value) error {
+	switch typ.Kind() {
+	case reflect.String:
+		return func(v string, field reflect.Value) error {
+			if lower {
+				v = strings.ToLower(v)
+			}
+			field.SetString(v)
+			return nil
+		}
+	case reflect.Int32, reflect.Int, reflect.Int64:
+		return func(v string, field reflect.Value) error {
+			vInt, err := strconv.Atoi(v)
+			field.SetInt(int64(vInt))
+			if v != "" && err != nil {
+				return fmt.Errorf("Cannot parse '%s' (%T) to type number", v, v)
 			}
+			return nil
 		}
+	case reflect.Bool:
+		return func(v string, field reflect.Value) error {
+			vBool, err := strconv.ParseBool(v)
+			field.SetBool(vBool)
+			if v != "" && err != nil {
+				return fmt.Errorf("Cannot parse '%s' (%T) to type boolean", v, v)
+			}
+			return nil
+		}
+	case reflect.Ptr:
+		elemType := typ.Elem()
+		elemConvert := compileQueryParamConverter(elemType, lower)
+		return func(v string, field reflect.Value) error {
+			if v == "" {
+				return nil
+			}
+			// allocate new value to pointer field
+			val := reflect.New(elemType)
+			field.Set(val)
+			if elemConvert == nil {
+				return nil
+			}
+			return elemConvert(v, val.Elem())
+		}
+	}
+	return nil
+}
 
-		parseDataTypeValue(field.Type(), field)
+func compileQueryParamEncoder(typ reflect.Type) func(field reflect.Value) (string, bool) {
+	switch typ.Kind() {
+	case reflect.String:
+		return func(field reflect.Value) (string, bool) { return field.String(), true }
+	case reflect.Int32, reflect.Int, reflect.Int64:
+		return func(field reflect.Value) (string, bool) { return strconv.FormatInt(field.Int(), 10), true }
+	case reflect.Bool:
+		return func(field reflect.Value) (string, bool) { return strconv.FormatBool(field.Bool()), true }
+	case reflect.Ptr:
+		elemEncode := compileQueryParamEncoder(typ.Elem())
+		return func(field reflect.Value) (string, bool) {
+			if field.IsNil() || elemEncode == nil {
+				return "", false
+			}
+			return elemEncode(field.Elem())
+		}
 	}
+	return nil
+}
 
-	if errs.HasError() {
-		return errs
+// ToQueryParam convert struct (or pointer of struct) to url query values, inverse of ParseFromQueryParam.
+// Key is taken from json tag (field without json tag is skipped), embedded struct field is promoted to parent,
+// nil pointer and value equal to value parsed from default tag is omitted
+func ToQueryParam(source interface{}) (query url.Values, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	val := reflect.ValueOf(source)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem() // take element if source type is pointer
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid source type %v: must struct", val.Kind())
 	}
 
+	query = url.Values{}
+	for _, f := range getQueryParamPlan(val.Type()) {
+		if f.key == "" {
+			continue
+		}
+
+		v, ok := f.encode(val.FieldByIndex(f.index))
+		if !ok || (f.defEncodedOK && v == f.defEncoded) {
+			continue
+		}
+		query.Set(f.key, v)
+	}
 	return
 }
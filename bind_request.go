@@ -0,0 +1,215 @@
+package golib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var (
+	// MaxBodySize maximum request body size (in bytes) read by BindRequest
+	MaxBodySize int64 = 10 << 20
+	// PathParamFunc get path parameter value from request, must be set based on router used by service
+	// (ex: func(req *http.Request, key string) string { return mux.Vars(req)[key] }) to bind field with path tag
+	PathParamFunc func(req *http.Request, key string) string
+
+	// ErrBodyTooLarge error when request body size is exceeding MaxBodySize
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrUnsupportedMediaType error when request Content-Type cannot be decoded
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// BindRequest bind http request to struct target (must in pointer). Request body is decoded based on Content-Type
+// (JSON, XML, application/x-www-form-urlencoded or multipart/form-data), form and multipart value is bound to field
+// with key in form tag (or json tag if field doesn't have any source tag) and multipart file to *multipart.FileHeader
+// or []*multipart.FileHeader field. Then value from path, query and header is bound to field with path, query and header tag.
+// Invalid value and failed validate tag rules (see ValidateStruct) will be returned in *MultiError with field key
+func BindRequest(req *http.Request, target interface{}) error {
+	refValue := reflect.ValueOf(target)
+	if refValue.Kind() != reflect.Ptr || refValue.IsNil() {
+		return fmt.Errorf("target is not pointer")
+	}
+	refValue = refValue.Elem()
+	if refValue.Kind() != reflect.Struct {
+		return fmt.Errorf("target is not pointer of struct")
+	}
+
+	errs := NewMultiError()
+
+	var form url.Values
+	var files map[string][]*multipart.FileHeader
+	if req.Body != nil && req.ContentLength != 0 {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(nil, req.Body, MaxBodySize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return ErrBodyTooLarge
+			}
+			return err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewBuffer(body)) // reuse body
+
+		if len(body) > 0 {
+			form, files, err = decodeRequestBody(req.Header.Get("Content-Type"), body, target, errs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	bindRequestStruct(req, form, files, refValue, errs)
+	validateStruct("", refValue, errs)
+
+	if errs.HasError() {
+		return errs
+	}
+	return nil
+}
+
+func decodeRequestBody(contentType string, body []byte, target interface{}, errs *MultiError) (url.Values, map[string][]*multipart.FileHeader, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil, ErrUnsupportedMediaType
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if err := json.Unmarshal(body, target); err != nil {
+			if e, ok := err.(*json.UnmarshalTypeError); ok && e.Field != "" {
+				errs.AppendCode(e.Field, ErrorCodeInvalidFormat, fmt.Errorf("Cannot parse '%s' to type %s", e.Value, e.Type))
+			} else {
+				errs.AppendCode("body", ErrorCodeInvalidFormat, err)
+			}
+		}
+
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		if err := xml.Unmarshal(body, target); err != nil {
+			errs.AppendCode("body", ErrorCodeInvalidFormat, err)
+		}
+
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			errs.AppendCode("body", ErrorCodeInvalidFormat, err)
+		}
+		return form, nil, nil
+
+	case mediaType == "multipart/form-data":
+		// body is already read and limited to MaxBodySize, so ReadForm keep file content in memory
+		// and bound file header can still be opened after RemoveAll clean up temporary file
+		multipartForm, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(MaxBodySize)
+		if err != nil {
+			errs.AppendCode("body", ErrorCodeInvalidFormat, err)
+			return nil, nil, nil
+		}
+		defer multipartForm.RemoveAll()
+		return url.Values(multipartForm.Value), multipartForm.File, nil
+
+	default:
+		return nil, nil, ErrUnsupportedMediaType
+	}
+
+	return nil, nil, nil
+}
+
+func bindRequestStruct(req *http.Request, form url.Values, files map[string][]*multipart.FileHeader, refValue reflect.Value, errs *MultiError) {
+	refType := refValue.Type()
+	for i := 0; i < refValue.NumField(); i++ {
+		field := refValue.Field(i)
+		typ := refType.Field(i)
+		if typ.PkgPath != "" && !typ.Anonymous { // unexported field
+			continue
+		}
+
+		if typ.Anonymous && field.Kind() == reflect.Struct && typ.Tag.Get("json") == "" { // embedded struct
+			bindRequestStruct(req, form, files, field, errs)
+			continue
+		}
+
+		var key string
+		var values []string
+		switch {
+		case typ.Tag.Get("path") != "":
+			key = typ.Tag.Get("path")
+			if PathParamFunc != nil {
+				values = []string{PathParamFunc(req, key)}
+			}
+		case typ.Tag.Get("query") != "":
+			key = typ.Tag.Get("query")
+			values = req.URL.Query()[key]
+		case typ.Tag.Get("header") != "":
+			key = typ.Tag.Get("header")
+			values = req.Header[http.CanonicalHeaderKey(key)]
+		case form == nil && files == nil:
+			continue
+		case typ.Tag.Get("form") != "":
+			key = typ.Tag.Get("form")
+			values = form[key]
+		default:
+			key = strings.Split(typ.Tag.Get("json"), ",")[0]
+			if key == "-" {
+				continue
+			}
+			if key == "" {
+				key = typ.Name
+			}
+			if isBindStruct(typ.Type) {
+				if field.Kind() == reflect.Ptr {
+					if !hasQueryPrefix(form, key+".") {
+						continue
+					}
+					if field.IsNil() {
+						field.Set(reflect.New(typ.Type.Elem()))
+					}
+					field = field.Elem()
+				}
+				bindQueryStruct(form, key+".", field, errs)
+				continue
+			}
+			values = form[key]
+		}
+
+		switch typ.Type {
+		case fileHeaderType:
+			if fh := files[key]; len(fh) > 0 {
+				field.Set(reflect.ValueOf(fh[0]))
+			}
+			continue
+		case fileHeaderSliceType:
+			if fh := files[key]; len(fh) > 0 {
+				field.Set(reflect.ValueOf(fh))
+			}
+			continue
+		}
+
+		var nonEmpty []string
+		for _, val := range values {
+			if val != "" {
+				nonEmpty = append(nonEmpty, val)
+			}
+		}
+		if len(nonEmpty) == 0 {
+			def, ok := typ.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			nonEmpty = []string{def}
+		}
+
+		if err := bindQueryValue(field, nonEmpty, typ.Tag); err != nil {
+			errs.AppendCode(key, ErrorCodeInvalidFormat, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package golib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindRequest(t *testing.T) {
+	type Pagination struct {
+		Page int `query:"page" default:"1"`
+	}
+	type payload struct {
+		Pagination
+		ID        string                  `path:"id"`
+		RequestID string                  `header:"X-Request-ID"`
+		Fields    []string                `query:"fields"`
+		Name      string                  `json:"name" xml:"name" validate:"required"`
+		Qty       int                     `json:"qty" xml:"qty" form:"quantity"`
+		Tags      []string                `json:"tags" xml:"tags"`
+		Image     *multipart.FileHeader   `form:"image"`
+		Documents []*multipart.FileHeader `form:"documents"`
+	}
+
+	PathParamFunc = func(req *http.Request, key string) string {
+		return strings.TrimPrefix(req.URL.Path, "/api/order/")
+	}
+	defer func() { PathParamFunc = nil }()
+
+	t.Run("Testcase #1: Positive, JSON body with path, query and header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/order/SO001?page=2&fields=id,name&fields=qty",
+			strings.NewReader(`{"name": "agungdp", "qty": 2, "tags": ["a", "b"]}`))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("X-Request-ID", "req-1")
+
+		var p payload
+		assert.NoError(t, BindRequest(req, &p))
+		assert.Equal(t, 2, p.Page)
+		assert.Equal(t, "SO001", p.ID)
+		assert.Equal(t, "req-1", p.RequestID)
+		assert.Equal(t, []string{"id", "name", "qty"}, p.Fields)
+		assert.Equal(t, "agungdp", p.Name)
+		assert.Equal(t, 2, p.Qty)
+		assert.Equal(t, []string{"a", "b"}, p.Tags)
+	})
+	t.Run("Testcase #2: Positive, form urlencoded body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/order/SO002", strings.NewReader("name=agungdp&quantity=3&tags=a&tags=b"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var p payload
+		assert.NoError(t, BindRequest(req, &p))
+		assert.Equal(t, 1, p.Page)
+		assert.Equal(t, "SO002", p.ID)
+		assert.Equal(t, "agungdp", p.Name)
+		assert.Equal(t, 3, p.Qty)
+		assert.Equal(t, []string{"a", "b"}, p.Tags)
+	})
+	t.Run("Testcase #3: Positive, multipart body with files", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		mw.WriteField("name", "agungdp")
+		mw.WriteField("quantity", "4")
+		fw, _ := mw.CreateFormFile("image", "image.png")
+		fw.Write([]byte("image"))
+		for _, name := range []string{"a.pdf", "b.pdf"} {
+			fw, _ = mw.CreateFormFile("documents", name)
+			fw.Write([]byte(name))
+		}
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/order/SO003", body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		var p payload
+		assert.NoError(t, BindRequest(req, &p))
+		assert.Equal(t, "agungdp", p.Name)
+		assert.Equal(t, 4, p.Qty)
+		assert.Equal(t, "image.png", p.Image.Filename)
+		assert.Equal(t, 2, len(p.Documents))
+
+		file, err := p.Image.Open()
+		assert.NoError(t, err)
+		content, _ := ioutil.ReadAll(file)
+		assert.Equal(t, "image", string(content))
+	})
+	t.Run("Testcase #4: Positive, XML body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/order/SO004",
+			strings.NewReader(`<payload><name>agungdp</name><qty>5</qty><tags>a</tags></payload>`))
+		req.Header.Set("Content-Type", "application/xml")
+
+		var p payload
+		assert.NoError(t, BindRequest(req, &p))
+		assert.Equal(t, "agungdp", p.Name)
+		assert.Equal(t, 5, p.Qty)
+		assert.Equal(t, []string{"a"}, p.Tags)
+	})
+	t.Run("Testcase #5: Negative, invalid field values", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/order/SO005?page=one", strings.NewReader(`{"qty": "two"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		var p payload
+		err := BindRequest(req, &p)
+		multiError, ok := err.(*MultiError)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"name", "page", "qty"}, sortedKeys(multiError.ToMap()))
+	})
+	t.Run("Testcase #6: Negative, body too large and unsupported media type", func(t *testing.T) {
+		defer func(size int64) { MaxBodySize = size }(MaxBodySize)
+		MaxBodySize = 5
+
+		req := httptest.NewRequest(http.MethodPost, "/api/order/SO006", strings.NewReader(`{"name": "agungdp"}`))
+		req.Header.Set("Content-Type", "application/json")
+		var p payload
+		assert.Equal(t, ErrBodyTooLarge, BindRequest(req, &p))
+
+		req = httptest.NewRequest(http.MethodPost, "/api/order/SO006", strings.NewReader(`name`))
+		req.Header.Set("Content-Type", "text/plain")
+		assert.Equal(t, ErrUnsupportedMediaType, BindRequest(req, &p))
+	})
+	t.Run("Testcase #7: Negative, invalid target type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/order/SO007", nil)
+		assert.Error(t, BindRequest(req, payload{}))
+	})
+}
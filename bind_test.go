@@ -1,10 +1,16 @@
 package golib
 
 import (
+	"errors"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"testing/quick"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -55,6 +61,15 @@ func TestBindQueryParam(t *testing.T) {
 			},
 			wantEqual: true,
 		},
+		{
+			name:       "Testcase #5: Positive, escaped plus and space and value contains '='",
+			queryParam: "orderId=0001=A&soNumber=2019:08:28T00:00:00%2B07:00%20SO",
+			target:     new(exampleParam),
+			wantResult: exampleParam{
+				OrderID: "0001=A", SoNumber: "2019:08:28T00:00:00+07:00 SO",
+			},
+			wantEqual: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -82,6 +97,104 @@ func TestBindQueryParam(t *testing.T) {
 	}
 }
 
+type textParam string
+
+func (p *textParam) UnmarshalText(b []byte) error {
+	if len(b) > 5 {
+		return errors.New("too long")
+	}
+	*p = textParam(strings.ToUpper(string(b)))
+	return nil
+}
+
+func TestBindQuery(t *testing.T) {
+	type Filter struct {
+		Name   string  `json:"name" lower:"true"`
+		Status *string `json:"status"`
+	}
+	type Embed struct {
+		Page  int    `json:"page" default:"1"`
+		Sort  string `json:"sort" default:"desc"`
+		Limit uint16 `json:"limit"`
+	}
+	type params struct {
+		Embed
+		IDs      []int         `json:"id"`
+		Tags     []string      `json:"tags"`
+		Price    float64       `json:"price"`
+		IsActive *bool         `json:"isActive"`
+		Since    time.Time     `json:"since"`
+		Date     time.Time     `json:"date" format:"2006-01-02"`
+		Timeout  time.Duration `json:"timeout"`
+		Code     textParam     `json:"code"`
+		Filter   Filter        `json:"filter"`
+		Other    *Filter       `json:"other"`
+		Ignored  string        `json:"-"`
+		NoTag    string
+	}
+
+	t.Run("Testcase #1: Positive", func(t *testing.T) {
+		query, err := url.ParseQuery("id=1&id=2,3&tags=a+b&price=10.5&isActive=true&limit=20" +
+			"&since=2019-08-28T00:00:00%2B07:00&date=2019-08-28&timeout=1m30s&code=abc" +
+			"&filter.name=AgungDP&filter.status=active&NoTag=x&Ignored=x")
+		assert.NoError(t, err)
+
+		var p params
+		assert.NoError(t, BindQuery(query, &p))
+		assert.Equal(t, 1, p.Page)
+		assert.Equal(t, "desc", p.Sort)
+		assert.Equal(t, uint16(20), p.Limit)
+		assert.Equal(t, []int{1, 2, 3}, p.IDs)
+		assert.Equal(t, []string{"a b"}, p.Tags)
+		assert.Equal(t, 10.5, p.Price)
+		assert.Equal(t, true, *p.IsActive)
+		assert.Equal(t, "2019-08-28T00:00:00+07:00", p.Since.Format(time.RFC3339))
+		assert.Equal(t, "2019-08-28", p.Date.Format("2006-01-02"))
+		assert.Equal(t, 90*time.Second, p.Timeout)
+		assert.Equal(t, textParam("ABC"), p.Code)
+		assert.Equal(t, "agungdp", p.Filter.Name)
+		assert.Equal(t, "active", *p.Filter.Status)
+		assert.Nil(t, p.Other)
+		assert.Equal(t, "", p.Ignored)
+		assert.Equal(t, "x", p.NoTag)
+	})
+	t.Run("Testcase #2: Positive, allocate nested pointer struct", func(t *testing.T) {
+		query, err := url.ParseQuery("other.name=test")
+		assert.NoError(t, err)
+
+		var p params
+		assert.NoError(t, BindQuery(query, &p))
+		assert.Equal(t, "test", p.Other.Name)
+		assert.Nil(t, p.Other.Status)
+	})
+	t.Run("Testcase #3: Negative, invalid values collected by field key", func(t *testing.T) {
+		query, err := url.ParseQuery("page=one&id=1,x&limit=-1&price=abc&isActive=terue&since=yesterday&timeout=10&code=toolong")
+		assert.NoError(t, err)
+
+		var p params
+		err = BindQuery(query, &p)
+		multiError, ok := err.(*MultiError)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"code", "id", "isActive", "limit", "page", "price", "since", "timeout"}, sortedKeys(multiError.ToMap()))
+		assert.Nil(t, p.IsActive)
+	})
+	t.Run("Testcase #4: Negative, invalid target type", func(t *testing.T) {
+		var p params
+		assert.Error(t, BindQuery(url.Values{}, p))
+
+		var s string
+		assert.Error(t, BindQuery(url.Values{}, &s))
+	})
+}
+
+func sortedKeys(m map[string]string) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
 func TestParseFromQueryParam(t *testing.T) {
 	type Embed struct {
 		Page   int    `json:"page"`
@@ -129,4 +242,145 @@ func TestParseFromQueryParam(t *testing.T) {
 		err = ParseFromQueryParam(urlVal, p)
 		assert.Error(t, err)
 	})
+	t.Run("Testcase #5: Positive, field plan is cached and reused by type", func(t *testing.T) {
+		urlVal, err := url.ParseQuery("page=2&sort=ASC")
+		assert.NoError(t, err)
+
+		var p1, p2 params
+		assert.NoError(t, ParseFromQueryParam(urlVal, &p1))
+		_, ok := queryParamPlans.Load(reflect.TypeOf(p1))
+		assert.True(t, ok)
+		assert.NoError(t, ParseFromQueryParam(urlVal, &p2))
+		assert.Equal(t, p1, p2)
+		assert.Equal(t, "asc", p2.Sort)
+		assert.Nil(t, p2.Ptr)
+	})
+}
+
+type roundTripEmbed struct {
+	Page  int    `json:"page" default:"1"`
+	Limit int64  `json:"limit"`
+	Sort  string `json:"sort,omitempty" default:"desc" lower:"true"`
+}
+
+type roundTripParam struct {
+	roundTripEmbed
+	Search   string  `json:"search"`
+	Status   string  `json:"status" lower:"true"`
+	IsActive bool    `json:"isActive" default:"true"`
+	Deleted  bool    `json:"deleted"`
+	Count    int32   `json:"count"`
+	Ptr      *string `json:"ptr"`
+	IntPtr   *int    `json:"intPtr"`
+	Ignored  string  `json:"-"`
+}
+
+// Generate random roundTripParam which can be represented in query param
+// (lower field in lowercase, field with default tag not empty and pointer to non empty value)
+func (roundTripParam) Generate(r *rand.Rand, size int) reflect.Value {
+	randString := func() string {
+		b := make([]rune, r.Intn(size+1))
+		for i := range b {
+			b[i] = rune(r.Intn(0x2000) + 1)
+		}
+		return string(b)
+	}
+	nonEmpty := func() string { return "x" + randString() }
+
+	p := roundTripParam{
+		roundTripEmbed: roundTripEmbed{Page: r.Int(), Limit: r.Int63() - r.Int63(), Sort: strings.ToLower(nonEmpty())},
+		Search:         randString(),
+		Status:         strings.ToLower(randString()),
+		IsActive:       r.Intn(2) == 0,
+		Deleted:        r.Intn(2) == 0,
+		Count:          r.Int31() - r.Int31(),
+	}
+	if r.Intn(2) == 0 {
+		ptr := nonEmpty()
+		p.Ptr = &ptr
+	}
+	if r.Intn(2) == 0 {
+		intPtr := r.Int()
+		p.IntPtr = &intPtr
+	}
+	return reflect.ValueOf(p)
+}
+
+func TestToQueryParam(t *testing.T) {
+	t.Run("Testcase #1: Positive, omit nil pointer and default value", func(t *testing.T) {
+		query, err := ToQueryParam(&roundTripParam{
+			roundTripEmbed: roundTripEmbed{Page: 1, Limit: 10, Sort: "asc"},
+			Search:         "laptop asus", IsActive: true, Ignored: "ignored",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "limit=10&search=laptop+asus&sort=asc", query.Encode())
+	})
+	t.Run("Testcase #2: Positive, encode then parse return original struct", func(t *testing.T) {
+		roundTrip := func(p roundTripParam) bool {
+			query, err := ToQueryParam(p)
+			if err != nil {
+				return false
+			}
+
+			// encode query to string to make sure encoded value is safe in url
+			parsedQuery, err := url.ParseQuery(query.Encode())
+			if err != nil {
+				return false
+			}
+
+			var result roundTripParam
+			if err := ParseFromQueryParam(parsedQuery, &result); err != nil {
+				return false
+			}
+			return reflect.DeepEqual(p, result)
+		}
+		assert.NoError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 500}))
+	})
+	t.Run("Testcase #3: Negative, source is not struct", func(t *testing.T) {
+		_, err := ToQueryParam("test")
+		assert.Error(t, err)
+	})
+}
+
+func BenchmarkParseFromQueryParam(b *testing.B) {
+	type Embed struct {
+		Page  int    `json:"page" default:"1"`
+		Limit int    `json:"limit" default:"10"`
+		Sort  string `json:"sort,omitempty" default:"desc" lower:"true"`
+	}
+	type params struct {
+		Embed
+		Search   string  `json:"search"`
+		Status   string  `json:"status"`
+		IsActive bool    `json:"isActive"`
+		Ptr      *string `json:"ptr"`
+	}
+	query, _ := url.ParseQuery("page=2&limit=20&sort=ASC&search=laptop&status=active&isActive=true&ptr=val")
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var p params
+			if err := ParseFromQueryParam(query, &p); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// baseline of cached plan, plan is compiled on every iteration
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			queryParamPlans.Range(func(key, _ interface{}) bool {
+				queryParamPlans.Delete(key)
+				return true
+			})
+			b.StartTimer()
+
+			var p params
+			if err := ParseFromQueryParam(query, &p); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
 }
@@ -0,0 +1,278 @@
+package compression
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Bhinneka/golib"
+	"github.com/andybalholm/brotli"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// EncodingBrotli brotli content coding
+	EncodingBrotli = "br"
+	// EncodingGzip gzip content coding
+	EncodingGzip = "gzip"
+	// EncodingDeflate deflate content coding
+	EncodingDeflate = "deflate"
+)
+
+var (
+	// MinSize minimum size of response body in bytes to be compressed, streaming response (flushed before reaching MinSize) is always compressed
+	MinSize = 1024
+	// GzipLevel compression level of gzip and deflate, must be set before serving request
+	GzipLevel = gzip.DefaultCompression
+	// BrotliLevel compression level of brotli, must be set before serving request
+	BrotliLevel = brotli.DefaultCompression
+	// Encodings supported content coding in order of server preference
+	Encodings = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	// SkipContentTypes prefix of content type which is already compressed
+	SkipContentTypes = []string{
+		"image/", "video/", "audio/", "font/woff",
+		"application/zip", "application/gzip", "application/x-gzip", "application/x-brotli",
+		"application/x-7z-compressed", "application/x-rar-compressed", "application/pdf",
+	}
+
+	pools = map[string]*sync.Pool{
+		EncodingBrotli: {New: func() interface{} { return brotli.NewWriterLevel(nil, BrotliLevel) }},
+		EncodingGzip: {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, GzipLevel)
+			return w
+		}},
+		EncodingDeflate: {New: func() interface{} {
+			w, _ := flate.NewWriter(nil, GzipLevel)
+			return w
+		}},
+	}
+)
+
+// encoder pooled compression writer
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Middleware compress response body with content coding negotiated from Accept-Encoding (brotli, gzip or deflate).
+// Body smaller than MinSize and content type in SkipContentTypes is not compressed, compression ratio
+// (uncompressed / compressed size) is set as "http.compression.ratio" tag of trace span in request context
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := NegotiateEncoding(req.Header.Get("Accept-Encoding"))
+		if encoding == "" || req.Method == http.MethodHead {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		cw := &responseWriter{ResponseWriter: w, encoding: encoding}
+		defer func() {
+			cw.close()
+			if span := opentracing.SpanFromContext(req.Context()); span != nil && cw.encoder != nil && cw.out.n > 0 {
+				span.SetTag("http.compression.encoding", encoding)
+				span.SetTag("http.compression.ratio", float64(cw.in)/float64(cw.out.n))
+			}
+		}()
+		h.ServeHTTP(cw, req)
+	})
+}
+
+// NegotiateEncoding return supported content coding with highest quality in Accept-Encoding header,
+// tie is broken with order of Encodings. Empty string is returned if response should not be compressed
+func NegotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qualities[coding] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, encoding := range Encodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// countWriter count bytes written to underlying writer
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// responseWriter buffer body until MinSize is reached or response is flushed to decide compression
+type responseWriter struct {
+	http.ResponseWriter
+	encoding string
+	code     int
+	buf      []byte
+	decided  bool
+	hijacked bool
+	encoder  encoder
+	out      countWriter
+	in       int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.decided {
+		return w.write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= MinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush start response (compressed if allowed regardless of MinSize) and flush it to client
+func (w *responseWriter) Flush() {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack let handler take over connection (ex: websocket), response is not compressed
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, golib.ErrHijackNotSupported
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// decide write header with compression if allowed and write buffered body
+func (w *responseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+
+	if compress && w.compressible() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag) // compressed representation is not byte-equal with uncompressed one
+		}
+
+		w.out.w = w.ResponseWriter
+		w.encoder = pools[w.encoding].Get().(encoder)
+		w.encoder.Reset(&w.out)
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+func (w *responseWriter) write(b []byte) (int, error) {
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	n, err := w.encoder.Write(b)
+	w.in += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) compressible() bool {
+	if w.code < http.StatusOK || w.code == http.StatusNoContent || w.code == http.StatusNotModified {
+		return false
+	}
+
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	// content type is sniffed before skip check, so body of already compressed type without Content-Type isn't compressed
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, skip := range SkipContentTypes {
+		if strings.HasPrefix(contentType, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// close write body smaller than MinSize uncompressed or finish compression and return encoder to pool
+func (w *responseWriter) close() {
+	if w.hijacked {
+		return
+	}
+	if !w.decided {
+		if w.code == 0 {
+			return // handler doesn't write response, let http server write default response
+		}
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(nil)
+		pools[w.encoding].Put(w.encoder)
+	}
+}
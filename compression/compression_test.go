@@ -0,0 +1,182 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bhinneka/golib"
+	"github.com/andybalholm/brotli"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		assert.NoError(t, err)
+		r = gr
+	case EncodingDeflate:
+		r = flate.NewReader(bytes.NewReader(body))
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name, accept, want string
+	}{
+		{name: "Testcase #1: Positive, server preference", accept: "gzip, deflate, br", want: EncodingBrotli},
+		{name: "Testcase #2: Positive, q-values", accept: "br;q=0.5, gzip", want: EncodingGzip},
+		{name: "Testcase #3: Positive, wildcard", accept: "*", want: EncodingBrotli},
+		{name: "Testcase #4: Positive, wildcard with rejected coding", accept: "br;q=0, *;q=0.5", want: EncodingGzip},
+		{name: "Testcase #5: Negative, identity only", accept: "identity", want: ""},
+		{name: "Testcase #6: Negative, empty", accept: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NegotiateEncoding(tt.accept))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	large := golib.NewHTTPResponseV2(http.StatusOK, "success", map[string]string{"description": strings.Repeat("ondel-ondel ", 200)})
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/small":
+			golib.NewHTTPResponseV2(http.StatusOK, "success").JSON(w)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte{0x89}, 2048))
+		case "/sniffed":
+			w.Write(append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), bytes.Repeat([]byte{0x89}, 2048)...))
+		default:
+			large.JSON(w)
+		}
+	}))
+
+	want := httptest.NewRecorder()
+	large.JSON(want)
+
+	tests := []struct {
+		name, path, accept, wantEncoding string
+	}{
+		{name: "Testcase #1: Positive, brotli", path: "/", accept: "gzip, br", wantEncoding: EncodingBrotli},
+		{name: "Testcase #2: Positive, gzip", path: "/", accept: "gzip", wantEncoding: EncodingGzip},
+		{name: "Testcase #3: Positive, deflate", path: "/", accept: "deflate", wantEncoding: EncodingDeflate},
+		{name: "Testcase #4: Negative, body under threshold", path: "/small", accept: "gzip", wantEncoding: ""},
+		{name: "Testcase #5: Negative, already compressed content type", path: "/image", accept: "gzip", wantEncoding: ""},
+		{name: "Testcase #6: Negative, not accepted", path: "/", accept: "", wantEncoding: ""},
+		{name: "Testcase #7: Negative, sniffed content type is already compressed", path: "/sniffed", accept: "gzip", wantEncoding: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			assert.Equal(t, tt.wantEncoding, rec.Header().Get("Content-Encoding"))
+
+			if tt.path == "/" {
+				assert.Equal(t, want.Body.String(), decompress(t, tt.wantEncoding, rec.Body.Bytes()))
+			}
+			if tt.path == "/sniffed" {
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestMiddlewareStreaming(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		items := make(chan interface{}, 2)
+		items <- map[string]int{"id": 1}
+		items <- map[string]int{"id": 2}
+		close(items)
+		golib.NewStreamWriter(golib.StreamNDJSON, http.StatusOK, "ok").Stream(w, req, items)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, EncodingGzip, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, golib.NDJSONContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"success\":true,\"code\":200,\"message\":\"ok\"}\n{\"id\":1}\n{\"id\":2}\n", decompress(t, EncodingGzip, rec.Body.Bytes()))
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _, err := w.(http.Hijacker).Hijack()
+		assert.NoError(t, err)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, req)
+	assert.True(t, rec.hijacked)
+
+	t.Run("Testcase #2: Negative, hijack not supported", func(t *testing.T) {
+		handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _, err := w.(http.Hijacker).Hijack()
+			assert.Equal(t, golib.ErrHijackNotSupported, err)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+}
+
+func TestMiddlewareSpanTag(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("GET /")
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(strings.Repeat("a", 4096)))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req = req.WithContext(opentracing.ContextWithSpan(req.Context(), span))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	span.Finish()
+
+	tags := tracer.FinishedSpans()[0].Tags()
+	assert.Equal(t, EncodingGzip, tags["http.compression.encoding"])
+	assert.True(t, tags["http.compression.ratio"].(float64) > 10)
+	assert.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+}
@@ -66,7 +66,7 @@ func InitDB() {
 		dbLogger = log.New()
 		lf := fmt.Sprintf("%s/logs/database.log", os.Getenv("STORAGE_DIR"))
 
-		f, err := os.OpenFile(lf, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		f, err := OpenRotatingFile(lf)
 		if err != nil {
 			panic(err)
 		}
@@ -117,7 +117,7 @@ func CreateDBConnection(descriptor string) *gorm.DB {
 	// set database log into file
 	if isDebug {
 		db.LogMode(true)
-		db.SetLogger(gorm.Logger{dbLogger})
+		db.SetLogger(gorm.Logger{LogWriter: dbLogger})
 	}
 
 	return db
@@ -1,18 +1,4 @@
+// Package golib is a collection of helpers for building HTTP services: response writer, request binding,
+// validation, error mapping, i18n and logging. Importing the package doesn't change global logging,
+// call InitLogger to configure logger
 package golib
-
-import (
-	"log/syslog"
-
-	log "github.com/sirupsen/logrus"
-	logrusSyslog "github.com/sirupsen/logrus/hooks/syslog"
-)
-
-func init() {
-
-	// init logger configuration
-	log.SetFormatter(&log.JSONFormatter{})
-	if syslogOutput, err := logrusSyslog.NewSyslogHook("", "", syslog.LOG_INFO, LogTag); err == nil {
-		log.AddHook(syslogOutput)
-	}
-
-}
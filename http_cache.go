@@ -0,0 +1,238 @@
+package golib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// DefaultCacheControl Cache-Control header set by ConditionalMiddleware if handler doesn't set it,
+	// "no-cache" let client store the response but revalidate it with ETag in every request
+	DefaultCacheControl = "no-cache"
+
+	// ErrHijackNotSupported error when underlying response writer doesn't implement http.Hijacker
+	ErrHijackNotSupported = errors.New("response writer does not support hijack")
+)
+
+// CacheOptions validator and caching headers of response
+type CacheOptions struct {
+	// ETag caller supplied entity tag (ex: `"v42"` or `W/"v42"`), strong ETag is computed from encoded body if empty
+	ETag string
+	// LastModified time of last modification, Last-Modified header is not set if zero
+	LastModified time.Time
+	// CacheControl Cache-Control header (ex: "public, max-age=60")
+	CacheControl string
+	// Vary additional request headers which the response vary by
+	Vary []string
+}
+
+// WithCache set validator and caching headers of response. Write respond 304 Not Modified without body
+// if request If-None-Match or If-Modified-Since match the response, JSON and XML only set the headers (see ConditionalMiddleware)
+func (resp *ResponseV2) WithCache(opts CacheOptions) *ResponseV2 {
+	resp.cache = &opts
+	return resp
+}
+
+// writeCached encode body to buffer and write it with headers from cache options, req is optional
+func (resp *ResponseV2) writeCached(w http.ResponseWriter, req *http.Request, encode func(buf *bytes.Buffer) error) error {
+	buf := new(bytes.Buffer)
+	if err := encode(buf); err != nil {
+		w.Header().Del("Content-Type")
+		return err
+	}
+
+	header := w.Header()
+	if resp.cache.CacheControl != "" {
+		header.Set("Cache-Control", resp.cache.CacheControl)
+	}
+	for _, v := range resp.cache.Vary {
+		header.Add("Vary", v)
+	}
+	if !resp.cache.LastModified.IsZero() {
+		header.Set("Last-Modified", resp.cache.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if resp.cache.ETag != "" {
+		header.Set("ETag", resp.cache.ETag)
+	}
+
+	return writeConditional(w, req, resp.Code, buf.Bytes())
+}
+
+// ComputeETag return strong ETag of body
+func ComputeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeConditional write response with ETag computed from body if it isn't set,
+// respond 304 Not Modified if request is GET or HEAD with matching If-None-Match or If-Modified-Since
+func writeConditional(w http.ResponseWriter, req *http.Request, code int, body []byte) error {
+	header := w.Header()
+	if code != http.StatusOK {
+		w.WriteHeader(code)
+		_, err := w.Write(body)
+		return err
+	}
+
+	if header.Get("ETag") == "" {
+		header.Set("ETag", ComputeETag(body))
+	}
+
+	if req != nil && isNotModified(req, header) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.WriteHeader(code)
+	_, err := w.Write(body)
+	return err
+}
+
+// isNotModified evaluate If-None-Match and then If-Modified-Since (RFC 7232 section 6)
+func isNotModified(req *http.Request, header http.Header) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := header.Get("ETag")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || (etag != "" && weakETag(candidate) == weakETag(etag)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
+
+// weakETag opaque tag of ETag for weak comparison
+func weakETag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
+
+// ConditionalMiddleware buffer successful response of GET and HEAD request to set strong ETag and Cache-Control (see DefaultCacheControl),
+// and respond 304 Not Modified if request If-None-Match or If-Modified-Since match, so handler calling JSON(w) get it without code changes.
+// Response is written through without buffering from the first Flush (streaming response) or Hijack of handler
+func ConditionalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		cw := &conditionalResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, req)
+		if cw.passthrough {
+			return
+		}
+		if cw.code == 0 {
+			cw.code = http.StatusOK
+		}
+
+		header := w.Header()
+		if cw.code == http.StatusOK {
+			if header.Get("Cache-Control") == "" {
+				header.Set("Cache-Control", DefaultCacheControl)
+			}
+			if header.Get("ETag") == "" {
+				// computed ETag is of negotiated and encoded body
+				addVary(header, "Accept", "Accept-Encoding")
+			}
+		}
+		writeConditional(w, req, cw.code, cw.buf.Bytes())
+	})
+}
+
+// addVary add values to Vary header which aren't in it yet
+func addVary(header http.Header, values ...string) {
+	existing := make(map[string]bool)
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			existing[http.CanonicalHeaderKey(strings.TrimSpace(field))] = true
+		}
+	}
+	for _, v := range values {
+		if !existing[http.CanonicalHeaderKey(v)] {
+			header.Add("Vary", v)
+		}
+	}
+}
+
+// hijackResponse take over connection of underlying response writer
+func hijackResponse(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrHijackNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// conditionalResponseWriter buffer response body until handler is finished or flush it
+type conditionalResponseWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	code        int
+	passthrough bool
+}
+
+func (w *conditionalResponseWriter) WriteHeader(code int) {
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+}
+
+func (w *conditionalResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush write buffered response and switch to passthrough, so streaming response is never buffered again
+func (w *conditionalResponseWriter) Flush() {
+	if !w.passthrough {
+		w.passthrough = true
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		w.ResponseWriter.WriteHeader(w.code)
+		if w.buf.Len() > 0 {
+			w.ResponseWriter.Write(w.buf.Bytes())
+			w.buf.Reset()
+		}
+	}
+	flushResponse(w.ResponseWriter)
+}
+
+// Hijack let handler take over connection (ex: websocket), middleware doesn't write response
+func (w *conditionalResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijackResponse(w.ResponseWriter)
+	if err == nil {
+		w.passthrough = true
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,179 @@
+package golib
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseV2WithCache(t *testing.T) {
+	lastModified := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	newResponse := func(opts CacheOptions) *ResponseV2 {
+		return NewHTTPResponseV2(http.StatusOK, "success", ExampleModel{OrderID: "061499700032"}).(*ResponseV2).WithCache(opts)
+	}
+
+	rec := httptest.NewRecorder()
+	assert.NoError(t, newResponse(CacheOptions{}).Write(rec, httptest.NewRequest(http.MethodGet, "/", nil)))
+	computedETag := rec.Header().Get("ETag")
+	assert.Equal(t, ComputeETag(rec.Body.Bytes()), computedETag)
+
+	tests := []struct {
+		name       string
+		opts       CacheOptions
+		header     map[string]string
+		method     string
+		wantCode   int
+		wantETag   string
+		wantHeader map[string]string
+	}{
+		{
+			name: "Testcase #1: Positive, computed etag match", method: http.MethodGet,
+			header: map[string]string{"If-None-Match": computedETag}, wantCode: http.StatusNotModified, wantETag: computedETag,
+		},
+		{
+			name: "Testcase #2: Positive, caller etag with weak comparison", opts: CacheOptions{ETag: `"v42"`, CacheControl: "public, max-age=60"}, method: http.MethodGet,
+			header: map[string]string{"If-None-Match": `"v41", W/"v42"`}, wantCode: http.StatusNotModified, wantETag: `"v42"`,
+			wantHeader: map[string]string{"Cache-Control": "public, max-age=60"},
+		},
+		{
+			name: "Testcase #3: Positive, not modified since", opts: CacheOptions{LastModified: lastModified}, method: http.MethodGet,
+			header: map[string]string{"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat)}, wantCode: http.StatusNotModified, wantETag: computedETag,
+			wantHeader: map[string]string{"Last-Modified": "Sun, 01 Mar 2020 10:00:00 GMT"},
+		},
+		{
+			name: "Testcase #4: Negative, etag take precedence over if-modified-since", opts: CacheOptions{LastModified: lastModified}, method: http.MethodGet,
+			header:   map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			wantCode: http.StatusOK, wantETag: computedETag,
+		},
+		{
+			name: "Testcase #5: Negative, modified", opts: CacheOptions{LastModified: lastModified}, method: http.MethodGet,
+			header:   map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)},
+			wantCode: http.StatusOK, wantETag: computedETag,
+		},
+		{
+			name: "Testcase #6: Negative, unsafe method", opts: CacheOptions{Vary: []string{"Authorization"}}, method: http.MethodPost,
+			header: map[string]string{"If-None-Match": "*"}, wantCode: http.StatusOK, wantETag: computedETag,
+			wantHeader: map[string]string{"Vary": "Accept"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, newResponse(tt.opts).Write(rec, req))
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantETag, rec.Header().Get("ETag"))
+			for k, v := range tt.wantHeader {
+				assert.Equal(t, v, rec.Header().Get(k))
+			}
+			if tt.wantCode == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+				assert.Empty(t, rec.Header().Get("Content-Type"))
+			} else {
+				assert.NotEmpty(t, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("Testcase #7: Positive, JSON only set headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		assert.NoError(t, newResponse(CacheOptions{ETag: `"v1"`, Vary: []string{"Accept-Language"}}).JSON(rec))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+		assert.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+		assert.Equal(t, `{"success":true,"code":200,"message":"success","data":{"orderId":"061499700032"}}`+"\n", rec.Body.String())
+	})
+}
+
+func TestConditionalMiddleware(t *testing.T) {
+	handler := ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		code := http.StatusOK
+		if req.URL.Path == "/missing" {
+			code = http.StatusNotFound
+		}
+		NewHTTPResponseV2(code, "success").JSON(w)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get("ETag")
+
+	t.Run("Testcase #1: Positive, etag and cache-control set on JSON(w)", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, ComputeETag(rec.Body.Bytes()), etag)
+		assert.Equal(t, DefaultCacheControl, rec.Header().Get("Cache-Control"))
+		assert.Equal(t, []string{"Accept", "Accept-Encoding"}, rec.Header().Values("Vary"))
+	})
+	t.Run("Testcase #2: Positive, not modified", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+	t.Run("Testcase #3: Negative, error response is not cached", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Header().Get("Cache-Control"))
+	})
+	t.Run("Testcase #4: Negative, streaming response is not buffered", func(t *testing.T) {
+		stream := ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			NewStreamWriter(StreamNDJSON, http.StatusOK, "ok").Stream(w, req, streamItems(1))
+		}))
+		rec := httptest.NewRecorder()
+		stream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.True(t, rec.Flushed)
+		assert.Empty(t, rec.Header().Get("ETag"))
+		assert.Equal(t, "{\"success\":true,\"code\":200,\"message\":\"ok\"}\n1\n", rec.Body.String())
+	})
+	t.Run("Testcase #5: Negative, flushed JSON array stream is not buffered", func(t *testing.T) {
+		stream := ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			NewStreamWriter(StreamJSONArray, http.StatusOK, "ok").Stream(w, req, streamItems(1, 2))
+		}))
+		rec := httptest.NewRecorder()
+		stream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.True(t, rec.Flushed)
+		assert.Empty(t, rec.Header().Get("ETag"))
+		assert.Equal(t, "{\"success\":true,\"code\":200,\"message\":\"ok\",\"data\":[1,2]}\n", rec.Body.String())
+	})
+	t.Run("Testcase #6: Negative, hijacked response is not written", func(t *testing.T) {
+		hijack := ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _, err := w.(http.Hijacker).Hijack()
+			assert.NoError(t, err)
+		}))
+		rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+		hijack.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+		assert.True(t, rec.hijacked)
+		assert.Empty(t, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Body.String())
+
+		unsupported := ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _, err := w.(http.Hijacker).Hijack()
+			assert.Equal(t, ErrHijackNotSupported, err)
+		}))
+		unsupported.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws", nil))
+	})
+}
+
+// hijackRecorder response recorder which support hijack
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
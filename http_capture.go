@@ -0,0 +1,482 @@
+package golib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const captureMask = "xxxxx"
+
+var (
+	// CaptureMaxBodySize default maximum size in bytes of captured request and response body, the rest is truncated
+	CaptureMaxBodySize = 64 << 10
+	// CaptureQueueSize default queue size of captured request and response waiting to be stored
+	CaptureQueueSize = 1024
+	// CaptureRedactHeaders default headers which value is masked
+	CaptureRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	// CaptureRedactFields default JSON and form fields which value is masked, compared case insensitively
+	CaptureRedactFields = []string{"password", "newPassword", "rePassword", "token", "accessToken", "access_token",
+		"refreshToken", "refresh_token", "secret", "clientSecret", "client_secret", "pin", "otp"}
+)
+
+// CaptureOptions options of CaptureMiddleware
+type CaptureOptions struct {
+	// Logger result logger to store request and response, GetResultLogger() is used if nil
+	Logger ResultLogger
+	// Code code of result logger, status code of response is used if nil
+	Code func(req *http.Request, status int) string
+	// MaxBodySize maximum size of captured body, CaptureMaxBodySize is used if 0
+	MaxBodySize int
+	// QueueSize queue size of captured request and response, CaptureQueueSize is used if 0.
+	// Captured request and response is dropped when queue is full
+	QueueSize int
+	// RedactHeaders headers which value is masked, CaptureRedactHeaders is used if nil
+	RedactHeaders []string
+	// RedactFields JSON and form fields which value is masked, CaptureRedactFields is used if nil
+	RedactFields []string
+	// SampleRate sampling rate (0 to 1) of route which is not in RouteSampleRates, 1 if 0
+	SampleRate float64
+	// RouteSampleRates sampling rate of route, key is path pattern (see path.Match) optionally prefixed
+	// with method, ex: "/orders/*" or "POST /orders", the longest matched pattern is used
+	RouteSampleRates map[string]float64
+	// StatusSampleRates sampling rate of status class (2 for 2xx, 5 for 5xx, ...) which is multiplied to route sampling rate,
+	// status class which is not in map has rate 1
+	StatusSampleRates map[int]float64
+}
+
+// capturedExchange captured request and response waiting to be stored, flushed is closed by worker
+// when exchange is a flush marker
+type capturedExchange struct {
+	code     string
+	request  []byte
+	response []byte
+	flushed  chan struct{}
+}
+
+// capturedRequest JSON of captured request
+type capturedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// capturedResponse JSON of captured response
+type capturedResponse struct {
+	Status   int               `json:"status"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	Duration int64             `json:"duration_ms"`
+}
+
+// Capture capture sampled request and response of handler and store them asynchronously with single worker,
+// call Close on shutdown so queued request and response are stored
+type Capture struct {
+	opts   CaptureOptions
+	masker *captureMasker
+	queue  *captureQueue
+
+	randMu sync.Mutex
+	random *rand.Rand
+}
+
+// NewCapture create capture with options and start its worker, default value is used for empty option
+func NewCapture(opts CaptureOptions) *Capture {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = CaptureMaxBodySize
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = CaptureQueueSize
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = CaptureRedactHeaders
+	}
+	if opts.RedactFields == nil {
+		opts.RedactFields = CaptureRedactFields
+	}
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 1
+	}
+
+	return &Capture{
+		opts:   opts,
+		masker: newCaptureMasker(opts.RedactHeaders, opts.RedactFields),
+		queue:  startCaptureWorker(opts.Logger, opts.QueueSize, "capture_middleware"),
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// CaptureMiddleware capture sampled request and response (status, headers and body up to MaxBodySize) with masked secret,
+// request body is read up to MaxBodySize before handler and replayed to it.
+// and store them asynchronously with RequestResponse of result logger in StoreRequestResponse format.
+// Its worker runs until process exit, use NewCapture to flush or close it
+func CaptureMiddleware(opts CaptureOptions) func(http.Handler) http.Handler {
+	return NewCapture(opts).Middleware
+}
+
+// Middleware capture sampled request and response of next, see CaptureMiddleware
+func (c *Capture) Middleware(next http.Handler) http.Handler {
+	opts, masker := &c.opts, c.masker
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rate := opts.routeSampleRate(req)
+		if rate <= 0 || c.sample() >= rate {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		start := time.Now()
+		reqBody := &captureBuffer{max: opts.MaxBodySize}
+		if req.Body != nil && req.Body != http.NoBody {
+			// body is read up to MaxBodySize before next, so it is captured even if handler doesn't read it
+			prefix, _ := ioutil.ReadAll(io.LimitReader(req.Body, int64(opts.MaxBodySize)+1))
+			reqBody.capture(prefix)
+			req.Body = &captureReadCloser{Reader: io.MultiReader(bytes.NewReader(prefix), req.Body), Closer: req.Body}
+		}
+		cw := &captureResponseWriter{ResponseWriter: w, body: captureBuffer{max: opts.MaxBodySize}}
+		next.ServeHTTP(cw, req)
+
+		// response of hijacked connection isn't written through response writer
+		if cw.hijacked {
+			return
+		}
+		if cw.status == 0 {
+			cw.status, cw.header = http.StatusOK, w.Header()
+		}
+		if statusRate, ok := opts.StatusSampleRates[cw.status/100]; ok && c.sample() >= statusRate {
+			return
+		}
+
+		code := strconv.Itoa(cw.status)
+		if opts.Code != nil {
+			code = opts.Code(req, cw.status)
+		}
+
+		request, _ := json.Marshal(capturedRequest{
+			Method:  req.Method,
+			URL:     masker.url(req.URL),
+			Headers: masker.headers(req.Header),
+			Body:    masker.body(req.Header.Get("Content-Type"), reqBody),
+		})
+		response, _ := json.Marshal(capturedResponse{
+			Status:   cw.status,
+			Headers:  masker.headers(cw.header),
+			Body:     masker.body(cw.header.Get("Content-Type"), &cw.body),
+			Duration: int64(time.Since(start) / time.Millisecond),
+		})
+
+		c.queue.enqueue(capturedExchange{code: code, request: request, response: response})
+	})
+}
+
+// Flush wait until request and response queued before Flush is called are stored or context is done
+func (c *Capture) Flush(ctx context.Context) error {
+	return c.queue.flush(ctx)
+}
+
+// Close stop accepting request and response and wait until queued ones are stored or context is done,
+// handler keeps serving request without capturing them
+func (c *Capture) Close(ctx context.Context) error {
+	return c.queue.close(ctx)
+}
+
+func (c *Capture) sample() float64 {
+	c.randMu.Lock()
+	defer c.randMu.Unlock()
+	return c.random.Float64()
+}
+
+// captureQueue queue of captured request and response stored by single worker, so result logger isn't used concurrently
+type captureQueue struct {
+	ch      chan capturedExchange
+	context string
+	done    chan struct{}
+
+	// mu guard closed and sending to ch, so ch isn't closed while it is sent
+	mu     sync.RWMutex
+	closed bool
+}
+
+// startCaptureWorker start worker which store captured request and response with logger (GetResultLogger() if nil)
+func startCaptureWorker(logger ResultLogger, queueSize int, context string) *captureQueue {
+	q := &captureQueue{ch: make(chan capturedExchange, queueSize), context: context, done: make(chan struct{})}
+	go func() {
+		defer close(q.done)
+		for exchange := range q.ch {
+			if exchange.flushed != nil {
+				close(exchange.flushed)
+				continue
+			}
+
+			l := logger
+			if l == nil {
+				l = GetResultLogger()
+			}
+			l.RequestResponse(exchange.code, formatRequestResponse(exchange.request, exchange.response))
+			if err := l.LastError(); err != nil {
+				Log(WarnLevel, err.Error(), q.context, "store")
+			}
+		}
+	}()
+	return q
+}
+
+// enqueue queue captured request and response, it is dropped if queue is full or closed
+func (q *captureQueue) enqueue(exchange capturedExchange) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return
+	}
+	select {
+	case q.ch <- exchange:
+	default:
+		Log(WarnLevel, "capture queue is full", q.context, "enqueue")
+	}
+}
+
+// flush wait until exchanges queued before flush are stored or context is done
+func (q *captureQueue) flush(ctx context.Context) error {
+	marker := capturedExchange{flushed: make(chan struct{})}
+
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return q.wait(ctx, q.done)
+	}
+	select {
+	case q.ch <- marker:
+	case <-ctx.Done():
+		q.mu.RUnlock()
+		return ctx.Err()
+	}
+	q.mu.RUnlock()
+
+	return q.wait(ctx, marker.flushed)
+}
+
+// close stop accepting exchange and wait until worker store queued exchanges and exit or context is done
+func (q *captureQueue) close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mu.Unlock()
+
+	return q.wait(ctx, q.done)
+}
+
+func (q *captureQueue) wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// routeSampleRate sampling rate of the longest route pattern matching "METHOD /path" or "/path"
+func (opts *CaptureOptions) routeSampleRate(req *http.Request) float64 {
+	rate := opts.SampleRate
+	longest := -1
+	for pattern, r := range opts.RouteSampleRates {
+		target := req.URL.Path
+		if strings.Contains(pattern, " ") {
+			target = req.Method + " " + req.URL.Path
+		}
+		if ok, _ := path.Match(pattern, target); ok && len(pattern) > longest {
+			rate, longest = r, len(pattern)
+		}
+	}
+	return rate
+}
+
+// captureBuffer keep the first max bytes written to it
+type captureBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *captureBuffer) capture(p []byte) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room <= 0 {
+			return
+		}
+		p = p[:room]
+	}
+	b.Write(p)
+}
+
+// captureReadCloser request body which replay captured prefix before the rest of original body
+type captureReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureResponseWriter copy status, headers and body written by handler
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     captureBuffer
+	hijacked bool
+}
+
+func (w *captureResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flush streaming response
+func (w *captureResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack let handler take over connection (ex: websocket), response of hijacked connection isn't captured
+func (w *captureResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijackResponse(w.ResponseWriter)
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// captureMasker mask redacted headers, query parameters and body fields
+type captureMasker struct {
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+	jsonRe        *regexp.Regexp
+}
+
+func newCaptureMasker(headers, fields []string) *captureMasker {
+	m := &captureMasker{redactHeaders: make(map[string]bool), redactFields: make(map[string]bool)}
+	for _, h := range headers {
+		m.redactHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	quoted := make([]string, 0, len(fields))
+	for _, f := range fields {
+		m.redactFields[strings.ToLower(f)] = true
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+	if len(quoted) > 0 {
+		// fallback for truncated or invalid JSON body, value is string, number, boolean, null or flat object or array
+		m.jsonRe = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)` +
+			`(?:"(?:[^"\\]|\\.)*"?|\{[^{}]*\}?|\[[^\[\]]*\]?|[^\s,{}\[\]"]+)`)
+	}
+	return m
+}
+
+func (m *captureMasker) headers(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(header))
+	for k, v := range header {
+		if m.redactHeaders[http.CanonicalHeaderKey(k)] {
+			result[k] = captureMask
+			continue
+		}
+		result[k] = strings.Join(v, ", ")
+	}
+	return result
+}
+
+func (m *captureMasker) url(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.RequestURI()
+	}
+	return u.Path + "?" + m.form(MaskPassword(u.RawQuery))
+}
+
+func (m *captureMasker) form(s string) string {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return s
+	}
+	for k := range values {
+		if m.redactFields[strings.ToLower(k)] {
+			values[k] = []string{captureMask}
+		}
+	}
+	return values.Encode()
+}
+
+// body masked body as JSON value: JSON body is kept as JSON, other body is JSON string
+func (m *captureMasker) body(contentType string, buf *captureBuffer) json.RawMessage {
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	b := buf.Bytes()
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		b = []byte(m.form(MaskPassword(string(b))))
+	case mediaType == JSONContentType || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		if !buf.truncated && json.Unmarshal(MaskJSONPassword(b), &v) == nil {
+			masked, _ := json.Marshal(m.json(v))
+			return masked
+		}
+		if m.jsonRe != nil {
+			b = m.jsonRe.ReplaceAll(b, []byte(`${1}"`+captureMask+`"`))
+		}
+	}
+
+	s := string(b)
+	if buf.truncated {
+		s += "...(truncated)"
+	}
+	out, _ := json.Marshal(s)
+	return out
+}
+
+// json mask value of redacted fields in decoded JSON recursively
+func (m *captureMasker) json(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if m.redactFields[strings.ToLower(k)] {
+				val[k] = captureMask
+				continue
+			}
+			val[k] = m.json(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = m.json(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,262 @@
+package golib
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// channelResultLogger result logger which send stored request and response to channel
+type channelResultLogger struct {
+	codes chan string
+	data  chan string
+}
+
+func newChannelResultLogger() *channelResultLogger {
+	return &channelResultLogger{codes: make(chan string, 10), data: make(chan string, 10)}
+}
+
+func (c *channelResultLogger) Store(code string, d []byte) string { return "" }
+func (c *channelResultLogger) Get(p string) string                { return "" }
+func (c *channelResultLogger) LastError() error                   { return nil }
+func (c *channelResultLogger) RequestResponse(code string, dt string) string {
+	c.codes <- code
+	c.data <- dt
+	return ""
+}
+
+// receive split stored data into request and response JSON
+func (c *channelResultLogger) receive(t *testing.T) (code string, request, response map[string]interface{}) {
+	select {
+	case code = <-c.codes:
+	case <-time.After(time.Second):
+		t.Fatal("request and response are not stored")
+	}
+	data := strings.TrimSuffix(strings.TrimPrefix(<-c.data, "REQUEST: "), "\n")
+	parts := strings.SplitN(data, " RESPONSE: ", 2)
+	assert.NoError(t, json.Unmarshal([]byte(parts[0]), &request))
+	assert.NoError(t, json.Unmarshal([]byte(parts[1]), &response))
+	return
+}
+
+func (c *channelResultLogger) assertEmpty(t *testing.T) {
+	select {
+	case code := <-c.codes:
+		t.Errorf("unexpected stored request and response with code %s", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCaptureMiddleware(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(b)
+	})
+
+	t.Run("Testcase #1: Positive, capture masked JSON request and response", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{Logger: logger})(echo)
+
+		req := httptest.NewRequest(http.MethodPost, "/login?token=abc&page=1", strings.NewReader(
+			`{"email":"user@example.com","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"email":"user@example.com","password":"secret"}`, rec.Body.String())
+
+		code, request, response := logger.receive(t)
+		assert.Equal(t, "201", code)
+		assert.Equal(t, "POST", request["method"])
+		assert.Equal(t, "/login?page=1&token=xxxxx", request["url"])
+		assert.Equal(t, "xxxxx", request["headers"].(map[string]interface{})["Authorization"])
+		assert.Equal(t, map[string]interface{}{"email": "user@example.com", "password": "xxxxx"}, request["body"])
+		assert.Equal(t, float64(201), response["status"])
+		assert.Equal(t, "xxxxx", response["headers"].(map[string]interface{})["Set-Cookie"])
+		assert.Equal(t, map[string]interface{}{"email": "user@example.com", "password": "xxxxx"}, response["body"])
+	})
+
+	t.Run("Testcase #2: Positive, redact nested field and form", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{
+			Logger:       logger,
+			RedactFields: []string{"cardNumber", "password"},
+			Code:         func(req *http.Request, status int) string { return "payment" },
+		})(echo)
+
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(
+			`{"items":[{"cardNumber":"4111111111111111","amount":10}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		code, request, _ := logger.receive(t)
+		assert.Equal(t, "payment", code)
+		assert.Equal(t, map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"cardNumber": "xxxxx", "amount": float64(10)},
+		}}, request["body"])
+
+		req = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader("user=a&password=b&cardNumber=4111"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		_, request, _ = logger.receive(t)
+		assert.Equal(t, "cardNumber=xxxxx&password=xxxxx&user=a", request["body"])
+	})
+
+	t.Run("Testcase #3: Positive, truncate body and mask truncated JSON", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{Logger: logger, MaxBodySize: 30})(echo)
+
+		body := `{"password":"secret","data":"` + strings.Repeat("a", 100) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, body, rec.Body.String())
+		_, request, _ := logger.receive(t)
+		assert.Equal(t, `{"password":"xxxxx","data":"a...(truncated)`, request["body"])
+	})
+
+	t.Run("Testcase #4: Positive, capture request body which isn't read by handler", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{Logger: logger})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		_, request, _ := logger.receive(t)
+		assert.Equal(t, map[string]interface{}{"id": float64(1)}, request["body"])
+	})
+
+	t.Run("Testcase #5: Positive, mask non-string value of truncated JSON", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{Logger: logger, MaxBodySize: 60, RedactFields: []string{"pin", "card", "otp"}})(echo)
+
+		body := `{"pin":123456,"card":{"number":"4111"},"otp":null,"data":"` + strings.Repeat("a", 100) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, body, rec.Body.String())
+		_, request, _ := logger.receive(t)
+		assert.Equal(t, `{"pin":"xxxxx","card":"xxxxx","otp":"xxxxx","data":"aa...(truncated)`, request["body"])
+	})
+
+	t.Run("Testcase #6: Positive, route sampling", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{
+			Logger:           logger,
+			SampleRate:       1,
+			RouteSampleRates: map[string]float64{"/health": 0, "/orders/*": 0, "POST /orders/*": 1},
+		})(echo)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+		logger.assertEmpty(t)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders/1", strings.NewReader("{}")))
+		_, request, _ := logger.receive(t)
+		assert.Equal(t, "/orders/1", request["url"])
+	})
+
+	t.Run("Testcase #7: Positive, status class sampling", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{
+			Logger:            logger,
+			StatusSampleRates: map[int]float64{2: 0, 5: 1},
+		})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+		logger.assertEmpty(t)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+		code, _, response := logger.receive(t)
+		assert.Equal(t, "500", code)
+		assert.Equal(t, float64(500), response["status"])
+	})
+
+	t.Run("Testcase #8: Positive, hijack is forwarded and not captured", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		handler := CaptureMiddleware(CaptureOptions{Logger: logger})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/ws" {
+				_, _, err := w.(http.Hijacker).Hijack()
+				assert.NoError(t, err)
+			}
+		}))
+
+		rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+		assert.True(t, rec.hijacked)
+		logger.assertEmpty(t)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		code, _, _ := logger.receive(t)
+		assert.Equal(t, "200", code)
+	})
+}
+
+func TestCapture(t *testing.T) {
+	t.Run("Testcase #1: Positive, flush and close store queued request and response", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		capture := NewCapture(CaptureOptions{Logger: logger})
+		handler := capture.Middleware(http.NotFoundHandler())
+
+		for i := 0; i < 3; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+		assert.NoError(t, capture.Flush(context.Background()))
+		assert.Len(t, logger.codes, 3)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NoError(t, capture.Close(context.Background()))
+		assert.Len(t, logger.codes, 4)
+	})
+
+	t.Run("Testcase #2: Positive, request after close is served without capture", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		capture := NewCapture(CaptureOptions{Logger: logger})
+		handler := capture.Middleware(http.NotFoundHandler())
+		assert.NoError(t, capture.Close(context.Background()))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, capture.Flush(context.Background()))
+		assert.NoError(t, capture.Close(context.Background()))
+		assert.Len(t, logger.codes, 0)
+	})
+
+	t.Run("Testcase #3: Negative, flush timeout", func(t *testing.T) {
+		logger := &channelResultLogger{codes: make(chan string), data: make(chan string)}
+		capture := NewCapture(CaptureOptions{Logger: logger})
+		capture.Middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, capture.Flush(ctx))
+		assert.Equal(t, context.DeadlineExceeded, capture.Close(ctx))
+		<-logger.codes
+		<-logger.data
+	})
+}
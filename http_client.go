@@ -0,0 +1,492 @@
+package golib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	ext "github.com/opentracing/opentracing-go/ext"
+)
+
+var (
+	// ClientMaxRetries default maximum retries of idempotent request
+	ClientMaxRetries = 3
+	// ClientRetryBaseDelay default base delay of exponential backoff
+	ClientRetryBaseDelay = 100 * time.Millisecond
+	// ClientRetryMaxDelay default maximum delay of exponential backoff
+	ClientRetryMaxDelay = 2 * time.Second
+	// ClientBreakerThreshold default consecutive failures of host before circuit breaker is open
+	ClientBreakerThreshold = 5
+	// ClientBreakerTimeout default duration of open circuit breaker before a trial request is allowed
+	ClientBreakerTimeout = 30 * time.Second
+
+	// ErrCircuitOpen error when request is rejected because circuit breaker of host is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+
+	// retryableStatus response status which is retried for idempotent request
+	retryableStatus = map[int]bool{
+		http.StatusTooManyRequests:    true,
+		http.StatusBadGateway:         true,
+		http.StatusServiceUnavailable: true,
+		http.StatusGatewayTimeout:     true,
+	}
+)
+
+// ClientOptions options of HTTP client transport
+type ClientOptions struct {
+	// Transport underlying round tripper, http.DefaultTransport is used if nil
+	Transport http.RoundTripper
+	// Timeout timeout of http.Client created by NewHTTPClient
+	Timeout time.Duration
+	// Logger result logger to store request and response, GetResultLogger() is used if nil
+	Logger ResultLogger
+	// DisableLog disable storing request and response
+	DisableLog bool
+	// Code code of result logger, hostname of request is used if nil
+	Code func(req *http.Request, status int) string
+	// MaxBodySize maximum size of stored body, CaptureMaxBodySize is used if 0
+	MaxBodySize int
+	// RedactHeaders headers which value is masked, CaptureRedactHeaders is used if nil
+	RedactHeaders []string
+	// RedactFields JSON and form fields which value is masked, CaptureRedactFields is used if nil
+	RedactFields []string
+	// MaxRetries maximum retries of idempotent request, ClientMaxRetries is used if 0, negative disable retry
+	MaxRetries int
+	// RetryBaseDelay base delay of exponential backoff, ClientRetryBaseDelay is used if 0
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay maximum delay of exponential backoff, ClientRetryMaxDelay is used if 0
+	RetryMaxDelay time.Duration
+	// BreakerThreshold consecutive failures (error or 5xx response) of host before circuit breaker is open,
+	// ClientBreakerThreshold is used if 0, negative disable circuit breaker
+	BreakerThreshold int
+	// BreakerTimeout duration of open circuit breaker before a trial request is allowed, ClientBreakerTimeout is used if 0
+	BreakerTimeout time.Duration
+}
+
+// ClientTransport http.RoundTripper which inject trace header, set span tags, store masked request and response,
+// retry idempotent request with exponential backoff and full jitter, and reject request to failing host with circuit breaker.
+// Call Close on shutdown so queued request and response are stored
+type ClientTransport struct {
+	opts   ClientOptions
+	masker *captureMasker
+	queue  *captureQueue
+
+	breakersMu sync.Mutex
+	breakers   map[string]*circuitBreaker
+}
+
+// NewHTTPClient create http.Client with ClientTransport
+func NewHTTPClient(opts ClientOptions) *http.Client {
+	return &http.Client{Transport: NewClientTransport(opts), Timeout: opts.Timeout}
+}
+
+// NewClientTransport create transport with options, default value is used for empty option
+func NewClientTransport(opts ClientOptions) *ClientTransport {
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = CaptureMaxBodySize
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = CaptureRedactHeaders
+	}
+	if opts.RedactFields == nil {
+		opts.RedactFields = CaptureRedactFields
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = ClientMaxRetries
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = ClientRetryBaseDelay
+	}
+	if opts.RetryMaxDelay <= 0 {
+		opts.RetryMaxDelay = ClientRetryMaxDelay
+	}
+	if opts.BreakerThreshold == 0 {
+		opts.BreakerThreshold = ClientBreakerThreshold
+	}
+	if opts.BreakerTimeout <= 0 {
+		opts.BreakerTimeout = ClientBreakerTimeout
+	}
+
+	t := &ClientTransport{
+		opts:     opts,
+		masker:   newCaptureMasker(opts.RedactHeaders, opts.RedactFields),
+		breakers: make(map[string]*circuitBreaker),
+	}
+	if !opts.DisableLog {
+		t.queue = startCaptureWorker(opts.Logger, CaptureQueueSize, "http_client")
+	}
+	return t
+}
+
+// RoundTrip send request. Body of request which can be retried is replayed with req.GetBody,
+// it is buffered in memory only if GetBody is nil
+func (t *ClientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	breaker := t.breaker(req.URL.Host)
+	if !breaker.allow() {
+		return nil, fmt.Errorf("%s: %w", req.URL.Host, ErrCircuitOpen)
+	}
+
+	body, replay, err := t.requestBody(req)
+	if err != nil {
+		breaker.release()
+		return nil, err
+	}
+	var reqBody *clientRequestBody
+	if body != nil && t.queue != nil {
+		reqBody = &clientRequestBody{ReadCloser: body, buf: captureBuffer{max: t.opts.MaxBodySize}}
+		body = reqBody
+	}
+
+	span, ctx := t.startSpan(req)
+	defer span.Finish()
+
+	start := time.Now()
+	var resp *http.Response
+	attempt := 0
+	for {
+		r := req.Clone(ctx)
+		if attempt == 0 && body != nil {
+			r.Body = body
+		} else if attempt > 0 && replay != nil {
+			if r.Body, err = replay(); err != nil {
+				breaker.release()
+				resp = nil
+				break
+			}
+		}
+		span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+		resp, err = t.opts.Transport.RoundTrip(r)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// request cancelled by caller doesn't tell health of host
+			breaker.release()
+		case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+			breaker.failure()
+		default:
+			breaker.success()
+		}
+
+		if attempt >= t.opts.MaxRetries || !t.retryable(req, resp, err) || (body != nil && replay == nil) {
+			break
+		}
+		attempt++
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if !t.wait(ctx, attempt) {
+			resp, err = nil, ctx.Err()
+			break
+		}
+		if !breaker.allow() {
+			resp, err = nil, fmt.Errorf("%s: %w", req.URL.Host, ErrCircuitOpen)
+			break
+		}
+	}
+
+	span.SetTag("http.retry_count", attempt)
+	span.SetTag("http.latency_ms", time.Since(start).Milliseconds())
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("event", "error", "message", err.Error())
+		t.store(req, reqBody, nil, nil, start)
+		return nil, err
+	}
+
+	ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		ext.Error.Set(span, true)
+	}
+	switch {
+	case t.queue == nil:
+	case resp.StatusCode == http.StatusSwitchingProtocols:
+		// body of upgraded connection is io.ReadWriteCloser used by caller, it isn't wrapped
+		t.store(req, reqBody, resp, nil, start)
+	default:
+		resp.Body = &clientResponseBody{
+			ReadCloser: resp.Body,
+			buf:        &captureBuffer{max: t.opts.MaxBodySize},
+			done:       func(buf *captureBuffer) { t.store(req, reqBody, resp, buf, start) },
+		}
+	}
+	return resp, nil
+}
+
+// requestBody body of the first attempt (nil if request has no body) and replay which return body of next attempt
+// (nil if request can't be retried). Body is read into memory only if request can be retried and GetBody is nil
+func (t *ClientTransport) requestBody(req *http.Request) (io.ReadCloser, func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil, nil
+	}
+	if t.opts.MaxRetries <= 0 || !idempotent(req) {
+		return req.Body, nil, nil
+	}
+	if req.GetBody != nil {
+		return req.Body, req.GetBody, nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	replay := func() (io.ReadCloser, error) { return ioutil.NopCloser(bytes.NewReader(b)), nil }
+	body, _ := replay()
+	return body, replay, nil
+}
+
+// Flush wait until request and response sent before Flush is called are stored or context is done
+func (t *ClientTransport) Flush(ctx context.Context) error {
+	if t.queue == nil {
+		return nil
+	}
+	return t.queue.flush(ctx)
+}
+
+// Close stop storing request and response and wait until queued ones are stored or context is done,
+// transport keeps sending request
+func (t *ClientTransport) Close(ctx context.Context) error {
+	if t.queue == nil {
+		return nil
+	}
+	return t.queue.close(ctx)
+}
+
+// BreakerOpen return true if circuit breaker of host (host:port of URL) is open
+func (t *ClientTransport) BreakerOpen(host string) bool {
+	b := t.breaker(host)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerOpen && time.Since(b.openedAt) < b.timeout
+}
+
+func (t *ClientTransport) startSpan(req *http.Request) (opentracing.Span, context.Context) {
+	operationName := fmt.Sprintf("HTTP %s %s", req.Method, req.URL.Host)
+	span, ctx := opentracing.StartSpanFromContext(req.Context(), operationName)
+	ext.SpanKindRPCClient.Set(span)
+	ext.HTTPMethod.Set(span, req.Method)
+	ext.HTTPUrl.Set(span, req.URL.Scheme+"://"+req.URL.Host+t.masker.url(req.URL))
+	ext.PeerHostname.Set(span, req.URL.Hostname())
+	return span, ctx
+}
+
+// idempotent request with idempotent method or Idempotency-Key header
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryable idempotent request is retried on network error and retryable status
+func (t *ClientTransport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if !idempotent(req) {
+		return false
+	}
+
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return retryableStatus[resp.StatusCode]
+}
+
+// wait sleep exponential backoff with full jitter before attempt, return false if context is done
+func (t *ClientTransport) wait(ctx context.Context, attempt int) bool {
+	delay := t.opts.RetryBaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > t.opts.RetryMaxDelay {
+		delay = t.opts.RetryMaxDelay
+	}
+	delay = time.Duration(rand.Int63n(int64(delay) + 1))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (t *ClientTransport) breaker(host string) *circuitBreaker {
+	t.breakersMu.Lock()
+	defer t.breakersMu.Unlock()
+
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &circuitBreaker{threshold: t.opts.BreakerThreshold, timeout: t.opts.BreakerTimeout}
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// store queue masked request and response (nil if request failed) to result logger
+func (t *ClientTransport) store(req *http.Request, body *clientRequestBody, resp *http.Response, respBody *captureBuffer, start time.Time) {
+	if t.queue == nil {
+		return
+	}
+
+	reqBody := &captureBuffer{max: t.opts.MaxBodySize}
+	if body != nil {
+		reqBody = body.captured()
+	}
+	request, _ := json.Marshal(capturedRequest{
+		Method:  req.Method,
+		URL:     req.URL.Scheme + "://" + req.URL.Host + t.masker.url(req.URL),
+		Headers: t.masker.headers(req.Header),
+		Body:    t.masker.body(req.Header.Get("Content-Type"), reqBody),
+	})
+
+	captured := capturedResponse{Duration: int64(time.Since(start) / time.Millisecond)}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+		captured.Status = resp.StatusCode
+		captured.Headers = t.masker.headers(resp.Header)
+		if respBody != nil {
+			captured.Body = t.masker.body(resp.Header.Get("Content-Type"), respBody)
+		}
+	}
+	response, _ := json.Marshal(captured)
+
+	code := req.URL.Hostname()
+	if t.opts.Code != nil {
+		code = t.opts.Code(req, status)
+	}
+	t.queue.enqueue(capturedExchange{code: code, request: request, response: response})
+}
+
+// clientRequestBody capture the first MaxBodySize bytes of request body read by transport,
+// transport may still read it in its own goroutine when exchange is stored
+type clientRequestBody struct {
+	io.ReadCloser
+	mu  sync.Mutex
+	buf captureBuffer
+}
+
+func (b *clientRequestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	b.buf.capture(p[:n])
+	b.mu.Unlock()
+	return n, err
+}
+
+// captured copy of body captured so far
+func (b *clientRequestBody) captured() *captureBuffer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := &captureBuffer{max: b.buf.max, truncated: b.buf.truncated}
+	c.Write(b.buf.Bytes())
+	return c
+}
+
+// clientResponseBody capture response body read by caller, done is called once when body is read to the end or closed
+type clientResponseBody struct {
+	io.ReadCloser
+	buf  *captureBuffer
+	once sync.Once
+	done func(buf *captureBuffer)
+}
+
+func (b *clientResponseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.capture(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.buf) })
+	}
+	return n, err
+}
+
+func (b *clientResponseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf) })
+	return err
+}
+
+// circuitBreaker state
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker open after threshold consecutive failures, after timeout one trial request is allowed (half open)
+// which close it on success or open it again on failure
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	timeout   time.Duration
+	state     int
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.threshold < 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.timeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.trial = true
+		return true
+	case breakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+// release cancel trial request which isn't sent
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure() {
+	if b.threshold < 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,298 @@
+package golib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStatusServer server which respond status of statuses in order, the last status is repeated
+func newStatusServer(hits *int32, statuses ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(atomic.AddInt32(hits, 1))
+		if n > len(statuses) {
+			n = len(statuses)
+		}
+		w.WriteHeader(statuses[n-1])
+	}))
+}
+
+// roundTripFunc round tripper of function
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// upgradeBody body of switching protocols response
+type upgradeBody struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (b *upgradeBody) Read(p []byte) (int, error) { return b.Reader.Read(p) }
+func (b *upgradeBody) Close() error               { return nil }
+
+func TestClientTransport(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	t.Run("Testcase #1: Positive, inject trace header, set span tags and store masked request and response", func(t *testing.T) {
+		tracer.Reset()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			assert.NotEmpty(t, req.Header.Get("Mockpfx-Ids-Traceid"))
+			b, _ := ioutil.ReadAll(req.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(b)
+		}))
+		defer server.Close()
+
+		logger := newChannelResultLogger()
+		client := NewHTTPClient(ClientOptions{Logger: logger, Code: func(req *http.Request, status int) string { return "partner" }})
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/login?token=abc", strings.NewReader(`{"email":"user@example.com","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer abc")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, `{"email":"user@example.com","password":"secret"}`, string(b))
+
+		spans := tracer.FinishedSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, uint16(http.StatusOK), spans[0].Tag("http.status_code"))
+		assert.Equal(t, server.URL+"/login?token=xxxxx", spans[0].Tag("http.url"))
+		assert.Equal(t, 0, spans[0].Tag("http.retry_count"))
+		assert.NotNil(t, spans[0].Tag("http.latency_ms"))
+
+		code, request, response := logger.receive(t)
+		assert.Equal(t, "partner", code)
+		assert.Equal(t, server.URL+"/login?token=xxxxx", request["url"])
+		assert.Equal(t, "xxxxx", request["headers"].(map[string]interface{})["Authorization"])
+		assert.Equal(t, map[string]interface{}{"email": "user@example.com", "password": "xxxxx"}, request["body"])
+		assert.Equal(t, float64(200), response["status"])
+		assert.Equal(t, map[string]interface{}{"email": "user@example.com", "password": "xxxxx"}, response["body"])
+	})
+
+	t.Run("Testcase #2: Positive, retry idempotent request", func(t *testing.T) {
+		var hits int32
+		server := newStatusServer(&hits, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+		defer server.Close()
+
+		client := NewHTTPClient(ClientOptions{DisableLog: true, RetryBaseDelay: time.Millisecond})
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), hits)
+	})
+
+	t.Run("Testcase #3: Positive, retry request with Idempotency-Key", func(t *testing.T) {
+		var hits int32
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			b, _ := ioutil.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+			if atomic.AddInt32(&hits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		client := NewHTTPClient(ClientOptions{DisableLog: true, RetryBaseDelay: time.Millisecond})
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("order"))
+		req.Header.Set("Idempotency-Key", "order-1")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"order", "order"}, bodies)
+	})
+
+	t.Run("Testcase #4: Negative, non idempotent request is not retried", func(t *testing.T) {
+		var hits int32
+		server := newStatusServer(&hits, http.StatusServiceUnavailable, http.StatusOK)
+		defer server.Close()
+
+		client := NewHTTPClient(ClientOptions{DisableLog: true, RetryBaseDelay: time.Millisecond})
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader("order"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), hits)
+	})
+
+	t.Run("Testcase #5: Negative, network error after retries", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		logger := newChannelResultLogger()
+		client := NewHTTPClient(ClientOptions{Logger: logger, MaxRetries: 2, RetryBaseDelay: time.Millisecond})
+		tracer.Reset()
+		_, err := client.Get(server.URL)
+		assert.Error(t, err)
+
+		spans := tracer.FinishedSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, true, spans[0].Tag("error"))
+		assert.Equal(t, 2, spans[0].Tag("http.retry_count"))
+
+		_, _, response := logger.receive(t)
+		assert.Equal(t, float64(0), response["status"])
+	})
+
+	t.Run("Testcase #6: Negative, circuit breaker", func(t *testing.T) {
+		var hits int32
+		server := newStatusServer(&hits, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+		defer server.Close()
+
+		transport := NewClientTransport(ClientOptions{DisableLog: true, MaxRetries: -1, BreakerThreshold: 2, BreakerTimeout: 50 * time.Millisecond})
+		client := &http.Client{Transport: transport}
+		host := strings.TrimPrefix(server.URL, "http://")
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(server.URL)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		}
+		assert.True(t, transport.BreakerOpen(host))
+
+		_, err := client.Get(server.URL)
+		assert.True(t, errors.Is(err, ErrCircuitOpen))
+		assert.Equal(t, int32(2), hits)
+
+		time.Sleep(60 * time.Millisecond)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.False(t, transport.BreakerOpen(host))
+	})
+
+	t.Run("Testcase #7: Positive, flush and close store queued request and response", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		logger := newChannelResultLogger()
+		transport := NewClientTransport(ClientOptions{Logger: logger})
+		client := &http.Client{Transport: transport}
+
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.NoError(t, transport.Flush(context.Background()))
+		assert.Len(t, logger.codes, 1)
+
+		assert.NoError(t, transport.Close(context.Background()))
+		resp, err = client.Get(server.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.NoError(t, transport.Flush(context.Background()))
+		assert.Len(t, logger.codes, 1)
+
+		assert.NoError(t, NewClientTransport(ClientOptions{DisableLog: true}).Close(context.Background()))
+	})
+
+	t.Run("Testcase #8: Positive, body without GetBody is buffered for retry", func(t *testing.T) {
+		var hits int32
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			b, _ := ioutil.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+			if atomic.AddInt32(&hits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		client := NewHTTPClient(ClientOptions{DisableLog: true, RetryBaseDelay: time.Millisecond})
+		req, _ := http.NewRequest(http.MethodPut, server.URL, ioutil.NopCloser(strings.NewReader("order")))
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"order", "order"}, bodies)
+	})
+
+	t.Run("Testcase #9: Positive, body of request which can't be retried isn't buffered", func(t *testing.T) {
+		body := ioutil.NopCloser(strings.NewReader("order"))
+		transport := NewClientTransport(ClientOptions{DisableLog: true, Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			assert.True(t, req.Body == body)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		})})
+
+		req, _ := http.NewRequest(http.MethodPost, "http://example.com", body)
+		_, err := transport.RoundTrip(req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Testcase #10: Positive, upgraded body is kept and exchange stored without close", func(t *testing.T) {
+		logger := newChannelResultLogger()
+		upgraded := &upgradeBody{Reader: strings.NewReader("")}
+		transport := NewClientTransport(ClientOptions{Logger: logger, Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/ws" {
+				return &http.Response{StatusCode: http.StatusSwitchingProtocols, Header: http.Header{}, Body: upgraded, Request: req}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("ok")), Request: req}, nil
+		})})
+
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com/ws", nil)
+		resp, err := transport.RoundTrip(req)
+		assert.NoError(t, err)
+		_, ok := resp.Body.(io.ReadWriteCloser)
+		assert.True(t, ok)
+		_, _, response := logger.receive(t)
+		assert.Equal(t, float64(http.StatusSwitchingProtocols), response["status"])
+
+		req, _ = http.NewRequest(http.MethodGet, "http://example.com/ok", nil)
+		resp, err = transport.RoundTrip(req)
+		assert.NoError(t, err)
+		ioutil.ReadAll(resp.Body)
+		_, _, response = logger.receive(t)
+		assert.Equal(t, "ok", response["body"])
+	})
+
+	t.Run("Testcase #11: Negative, request cancelled by caller doesn't open circuit breaker", func(t *testing.T) {
+		transport := NewClientTransport(ClientOptions{DisableLog: true, BreakerThreshold: 1, Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		})})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+		_, err := transport.RoundTrip(req)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.False(t, transport.BreakerOpen("example.com"))
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("Testcase #1: Positive, single trial request when half open", func(t *testing.T) {
+		b := &circuitBreaker{threshold: 1, timeout: time.Millisecond}
+		b.failure()
+		assert.False(t, b.allow())
+
+		time.Sleep(2 * time.Millisecond)
+		assert.True(t, b.allow())
+		assert.False(t, b.allow())
+
+		b.failure()
+		assert.False(t, b.allow())
+	})
+
+	t.Run("Testcase #2: Positive, disabled", func(t *testing.T) {
+		b := &circuitBreaker{threshold: -1}
+		b.failure()
+		assert.True(t, b.allow())
+	})
+}
@@ -0,0 +1,432 @@
+package golib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonapi"
+)
+
+const (
+	// JSONContentType media type of JSON response
+	JSONContentType = "application/json"
+	// XMLContentType media type of XML response
+	XMLContentType = "application/xml"
+	// MessagePackContentType media type of MessagePack response
+	MessagePackContentType = "application/msgpack"
+	// CBORContentType media type of CBOR response (RFC 8949)
+	CBORContentType = "application/cbor"
+	// JSONAPIContentType media type of JSON:API response
+	JSONAPIContentType = "application/vnd.api+json"
+)
+
+// ResponseEncoder encode response value (ex: *ResponseV2) to writer
+type ResponseEncoder func(w io.Writer, v interface{}) error
+
+type responseEncoder struct {
+	contentType string
+	encode      ResponseEncoder
+}
+
+var (
+	responseEncoderMu sync.RWMutex
+	// responseEncoders list of encoder in server preference order for content negotiation
+	responseEncoders = []responseEncoder{
+		{JSONContentType, encodeJSON},
+		{XMLContentType, encodeXML},
+		{MessagePackContentType, EncodeMessagePack},
+		{CBORContentType, EncodeCBOR},
+		{JSONAPIContentType, encodeJSONAPI},
+	}
+)
+
+// RegisterResponseEncoder register encoder for content type used in content negotiation (ex: application/x-protobuf),
+// existing encoder with same content type will be replaced
+func RegisterResponseEncoder(contentType string, encoder ResponseEncoder) {
+	responseEncoderMu.Lock()
+	defer responseEncoderMu.Unlock()
+
+	contentType = strings.ToLower(contentType)
+	for i, enc := range responseEncoders {
+		if enc.contentType == contentType {
+			responseEncoders[i].encode = encoder
+			return
+		}
+	}
+	responseEncoders = append(responseEncoders, responseEncoder{contentType, encoder})
+}
+
+// getResponseEncoder negotiate registered encoder with Accept header value
+func getResponseEncoder(accept string) (string, ResponseEncoder) {
+	responseEncoderMu.RLock()
+	defer responseEncoderMu.RUnlock()
+
+	offers := make([]string, len(responseEncoders))
+	for i, enc := range responseEncoders {
+		offers[i] = enc.contentType
+	}
+
+	contentType := NegotiateContentType(accept, offers)
+	for _, enc := range responseEncoders {
+		if enc.contentType == contentType {
+			return enc.contentType, enc.encode
+		}
+	}
+	return "", nil
+}
+
+// NegotiateContentType choose best offer for Accept header value (with q-values), offer order is server preference
+// when quality is equal. First offer is returned if accept is empty and empty string is returned if nothing match
+func NegotiateContentType(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		r := mediaRange{q: 1}
+		if i := strings.Index(mediaType, "/"); i >= 0 {
+			r.typ, r.subtype = mediaType[:i], mediaType[i+1:]
+		} else {
+			r.typ, r.subtype = mediaType, "*"
+		}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	var best string
+	var bestQ float64
+	for _, offer := range offers {
+		i := strings.Index(offer, "/")
+		if i < 0 {
+			continue
+		}
+		typ, subtype := strings.ToLower(offer[:i]), strings.ToLower(offer[i+1:])
+
+		// quality of offer is taken from most specific matching media range
+		specificity, q := -1, 0.0
+		for _, r := range ranges {
+			var s int
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				specificity, q = s, r.q
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func encodeXML(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// encodeJSONAPI encode ResponseV2 to JSON:API document, data must be jsonapi.Payloader (see MarshalConvertOnePayload)
+// or struct with jsonapi tag, meta is set as document meta and failed response is encoded as errors document
+func encodeJSONAPI(w io.Writer, v interface{}) error {
+	resp, ok := v.(*ResponseV2)
+	if !ok {
+		return encodeJSON(w, v)
+	}
+
+	if !resp.Success {
+		errs, _ := resp.Errors.(map[string]string)
+		errorObjects := JSONAPIErrors(resp.Code, resp.Message, errs)
+		if len(errorObjects) == 0 {
+			errorObjects = append(errorObjects, &JSONAPIErrorObject{Status: strconv.Itoa(resp.Code), Title: resp.Message})
+		}
+		return json.NewEncoder(w).Encode(map[string]interface{}{"errors": errorObjects})
+	}
+
+	var payload jsonapi.Payloader
+	switch data := resp.Data.(type) {
+	case jsonapi.Payloader:
+		payload = data
+	case nil, struct{}:
+		payload = &jsonapi.OnePayload{}
+	default:
+		p, err := jsonapi.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = p
+	}
+
+	if resp.Meta != nil {
+		generic, err := toGenericValue(resp.Meta)
+		if err != nil {
+			return err
+		}
+		if m, ok := generic.(map[string]interface{}); ok {
+			meta := jsonapi.Meta(m)
+			switch p := payload.(type) {
+			case *jsonapi.OnePayload:
+				p.Meta = &meta
+			case *jsonapi.ManyPayload:
+				p.Meta = &meta
+			}
+		}
+	}
+
+	return json.NewEncoder(w).Encode(payload)
+}
+
+// toGenericValue convert value to generic JSON value (nil, bool, json.Number, string, []interface{} and map[string]interface{})
+// so value is encoded with same key and format as JSON response
+func toGenericValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err = decoder.Decode(&generic)
+	return generic, err
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EncodeMessagePack encode value to MessagePack with key and format from JSON encoding, map key is sorted
+func EncodeMessagePack(w io.Writer, v interface{}) error {
+	generic, err := toGenericValue(v)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writeMessagePack(buf, generic); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeMessagePack(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if val {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			writeMessagePackInt(buf, n)
+		} else if n, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, n)
+		} else if f, err := val.Float64(); err == nil {
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		} else {
+			return err
+		}
+	case string:
+		writeMessagePackHeader(buf, len(val), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(val)
+	case []interface{}:
+		writeMessagePackHeader(buf, len(val), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range val {
+			if err := writeMessagePack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		writeMessagePackHeader(buf, len(val), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range sortedMapKeys(val) {
+			writeMessagePack(buf, key)
+			if err := writeMessagePack(buf, val[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode type %T to msgpack", v)
+	}
+	return nil
+}
+
+// writeMessagePackHeader write fix, 8 (if code exist), 16 or 32 bit length header
+func writeMessagePackHeader(buf *bytes.Buffer, length int, fix byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case length < fixLimit:
+		buf.WriteByte(fix | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buf.Write([]byte{code8, byte(length)})
+	case length <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+}
+
+func writeMessagePackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		buf.WriteByte(byte(n))
+	case n >= 0 && n <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(n)})
+	case n >= 0 && n <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n >= 0 && n <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	case n >= 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(n))
+	case n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(int8(n))})
+	case n >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// EncodeCBOR encode value to CBOR (RFC 8949) with key and format from JSON encoding, map key is sorted
+func EncodeCBOR(w io.Writer, v interface{}) error {
+	generic, err := toGenericValue(v)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writeCBOR(buf, generic); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeCBOR(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if val {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			if n >= 0 {
+				writeCBORHeader(buf, 0, uint64(n))
+			} else {
+				writeCBORHeader(buf, 1, uint64(-(n + 1)))
+			}
+		} else if n, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			writeCBORHeader(buf, 0, n)
+		} else if f, err := val.Float64(); err == nil {
+			buf.WriteByte(0xfb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		} else {
+			return err
+		}
+	case string:
+		writeCBORHeader(buf, 3, uint64(len(val)))
+		buf.WriteString(val)
+	case []interface{}:
+		writeCBORHeader(buf, 4, uint64(len(val)))
+		for _, item := range val {
+			if err := writeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		writeCBORHeader(buf, 5, uint64(len(val)))
+		for _, key := range sortedMapKeys(val) {
+			writeCBOR(buf, key)
+			if err := writeCBOR(buf, val[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode type %T to cbor", v)
+	}
+	return nil
+}
+
+func writeCBORHeader(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
@@ -0,0 +1,180 @@
+package golib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{JSONContentType, XMLContentType, MessagePackContentType, CBORContentType, JSONAPIContentType}
+	tests := []struct {
+		name, accept, want string
+	}{
+		{name: "Testcase #1: empty accept", accept: "", want: JSONContentType},
+		{name: "Testcase #2: wildcard", accept: "*/*", want: JSONContentType},
+		{name: "Testcase #3: exact", accept: "application/xml", want: XMLContentType},
+		{name: "Testcase #4: q-values", accept: "application/json;q=0.5, application/cbor", want: CBORContentType},
+		{name: "Testcase #5: specific range override wildcard", accept: "application/*;q=0.8, application/json;q=0, */*;q=0.1", want: XMLContentType},
+		{name: "Testcase #6: browser accept", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: XMLContentType},
+		{name: "Testcase #7: case insensitive", accept: "Application/VND.API+JSON", want: JSONAPIContentType},
+		{name: "Testcase #8: not acceptable", accept: "text/html", want: ""},
+		{name: "Testcase #9: all rejected", accept: "*/*;q=0", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NegotiateContentType(tt.accept, offers))
+		})
+	}
+}
+
+func TestEncodeMessagePack(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: nil, want: "c0"},
+		{value: true, want: "c3"},
+		{value: 1, want: "01"},
+		{value: 200, want: "ccc8"},
+		{value: 70000, want: "ce00011170"},
+		{value: -1, want: "ff"},
+		{value: -200, want: "d1ff38"},
+		{value: 1.5, want: "cb3ff8000000000000"},
+		{value: "a", want: "a161"},
+		{value: []int{1, 2}, want: "920102"},
+		{value: map[string]interface{}{"b": false, "a": 1}, want: "82a16101a162c2"},
+		{value: ExampleModel{OrderID: "1"}, want: "81a76f726465724964a131"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.value), func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			assert.NoError(t, EncodeMessagePack(buf, tt.value))
+			assert.Equal(t, tt.want, hex.EncodeToString(buf.Bytes()))
+		})
+	}
+
+	assert.Error(t, EncodeMessagePack(new(bytes.Buffer), make(chan int)))
+}
+
+func TestEncodeCBOR(t *testing.T) {
+	// test vectors from RFC 8949 appendix A
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: 0, want: "00"},
+		{value: 23, want: "17"},
+		{value: 24, want: "1818"},
+		{value: 1000000, want: "1a000f4240"},
+		{value: uint64(18446744073709551615), want: "1bffffffffffffffff"},
+		{value: -1, want: "20"},
+		{value: -1000, want: "3903e7"},
+		{value: 1.1, want: "fb3ff199999999999a"},
+		{value: false, want: "f4"},
+		{value: nil, want: "f6"},
+		{value: "IETF", want: "6449455446"},
+		{value: []int{1, 2, 3}, want: "83010203"},
+		{value: map[string]interface{}{"b": []int{2, 3}, "a": 1}, want: "a26161016162820203"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.value), func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			assert.NoError(t, EncodeCBOR(buf, tt.value))
+			assert.Equal(t, tt.want, hex.EncodeToString(buf.Bytes()))
+		})
+	}
+
+	assert.Error(t, EncodeCBOR(new(bytes.Buffer), make(chan int)))
+}
+
+type jsonAPIModel struct {
+	ID   string `jsonapi:"primary,orders"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func TestHTTPResponseWrite(t *testing.T) {
+	t.Run("Testcase #1: Positive, negotiate registered encoder", func(t *testing.T) {
+		for accept, contentType := range map[string]string{
+			"":                     JSONContentType,
+			"application/xml":      XMLContentType,
+			"application/msgpack":  MessagePackContentType,
+			"application/cbor;q=1": CBORContentType,
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+
+			resp := NewHTTPResponseV2(http.StatusCreated, "success")
+			assert.NoError(t, WriteHTTPResponse(w, req, resp))
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			assert.NotEmpty(t, w.Body.Bytes())
+		}
+	})
+	t.Run("Testcase #2: Positive, runtime registered encoder", func(t *testing.T) {
+		RegisterResponseEncoder("text/plain", func(w io.Writer, v interface{}) error {
+			_, err := fmt.Fprint(w, v.(*ResponseV2).Message)
+			return err
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/plain")
+		w := httptest.NewRecorder()
+		assert.NoError(t, WriteHTTPResponse(w, req, NewHTTPResponseV2(http.StatusOK, "plain message")))
+		assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+		assert.Equal(t, "plain message", w.Body.String())
+	})
+	t.Run("Testcase #3: Positive, JSON:API document", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", JSONAPIContentType)
+
+		w := httptest.NewRecorder()
+		resp := NewHTTPResponseV2(http.StatusOK, "success", []*jsonAPIModel{{ID: "1", Name: "order"}}, Meta{Page: 1, Limit: 10, TotalRecords: 1, TotalPages: 1})
+		assert.NoError(t, WriteHTTPResponse(w, req, resp))
+		assert.Equal(t, JSONAPIContentType, w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"data":[{"type":"orders","id":"1","attributes":{"name":"order"}}],`+
+			`"meta":{"limit":10,"page":1,"totalPages":1,"totalRecords":1}}`, w.Body.String())
+
+		multiError := NewMultiError()
+		multiError.Append("name", ErrRequired)
+		w = httptest.NewRecorder()
+		assert.NoError(t, WriteHTTPResponse(w, req, NewHTTPResponseV2(http.StatusBadRequest, "invalid", multiError)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"errors":[{"title":"invalid","detail":"is required","status":"400","source":{"pointer":"/data/attributes/name"}}]}`, w.Body.String())
+	})
+	t.Run("Testcase #4: Negative, not acceptable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "image/png")
+		w := httptest.NewRecorder()
+
+		assert.NoError(t, WriteHTTPResponse(w, req, NewHTTPResponseV2(http.StatusOK, "success")))
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+		var resp ResponseV2
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.False(t, resp.Success)
+		assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+	})
+	t.Run("Testcase #5: Negative, encoder error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", MessagePackContentType)
+		w := httptest.NewRecorder()
+		assert.Error(t, WriteHTTPResponse(w, req, NewHTTPResponseV2(http.StatusOK, "success", make(chan int))))
+	})
+	t.Run("Testcase #6: Positive, problem details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/xml")
+		w := httptest.NewRecorder()
+		assert.NoError(t, NewProblemDetails(http.StatusNotFound, "", "", "").Write(w, req))
+		assert.Equal(t, ProblemXMLContentType, w.Header().Get("Content-Type"))
+	})
+}
@@ -0,0 +1,138 @@
+package golib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrNotFound error when requested data doesn't exist
+	ErrNotFound = errors.New(ErrorDataNotFound)
+	// ErrConflict error when data conflict with existing state, ex: duplicate key
+	ErrConflict = errors.New("data conflict")
+	// ErrUnauthorized error when request is not authenticated
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden error when request is not allowed to access the resource
+	ErrForbidden = errors.New("forbidden")
+	// ErrTimeout error when process exceeded its deadline
+	ErrTimeout = errors.New("timeout")
+
+	// TraceErrorFunc mark trace span in context as error, set by package tracer (tracer.SetError) when it is imported
+	TraceErrorFunc func(ctx context.Context, err error)
+
+	// DefaultErrorMapping mapping used by NewHTTPErrorResponse when error is not registered
+	DefaultErrorMapping = ErrorMapping{Status: http.StatusInternalServerError, MessageKey: "error.internal", LogLevel: ErrorLevel}
+
+	errorMappingMu sync.RWMutex
+	errorMappings  []errorMappingEntry
+)
+
+// ErrorMapping mapping of error to http response
+type ErrorMapping struct {
+	// Status http status code of response
+	Status int
+	// MessageKey message key in DefaultCatalog of response message, error message is used if empty
+	MessageKey string
+	// LogLevel level of error log
+	LogLevel Level
+}
+
+type errorMappingEntry struct {
+	target  error
+	errType reflect.Type
+	mapping ErrorMapping
+}
+
+func init() {
+	RegisterError(ErrNotFound, ErrorMapping{Status: http.StatusNotFound, MessageKey: "error.not_found", LogLevel: InfoLevel})
+	RegisterError(ErrConflict, ErrorMapping{Status: http.StatusConflict, MessageKey: "error.conflict", LogLevel: InfoLevel})
+	RegisterError(ErrUnauthorized, ErrorMapping{Status: http.StatusUnauthorized, MessageKey: "error.unauthorized", LogLevel: WarnLevel})
+	RegisterError(ErrForbidden, ErrorMapping{Status: http.StatusForbidden, MessageKey: "error.forbidden", LogLevel: WarnLevel})
+	RegisterError(ErrTimeout, ErrorMapping{Status: http.StatusGatewayTimeout, MessageKey: "error.timeout", LogLevel: WarnLevel})
+	RegisterError(context.DeadlineExceeded, ErrorMapping{Status: http.StatusGatewayTimeout, MessageKey: "error.timeout", LogLevel: WarnLevel})
+	RegisterError(ErrBodyTooLarge, ErrorMapping{Status: http.StatusRequestEntityTooLarge, MessageKey: "error.body_too_large", LogLevel: InfoLevel})
+	RegisterError(ErrUnsupportedMediaType, ErrorMapping{Status: http.StatusUnsupportedMediaType, MessageKey: "error.unsupported_media_type", LogLevel: InfoLevel})
+	RegisterErrorType((*MultiError)(nil), ErrorMapping{Status: http.StatusBadRequest, MessageKey: "error.validation", LogLevel: InfoLevel})
+}
+
+// RegisterError register mapping of sentinel error, error is matched with errors.Is
+// and mapping registered later take precedence
+func RegisterError(target error, mapping ErrorMapping) {
+	errorMappingMu.Lock()
+	defer errorMappingMu.Unlock()
+	errorMappings = append([]errorMappingEntry{{target: target, mapping: mapping}}, errorMappings...)
+}
+
+// RegisterErrorType register mapping of error type, target is value of the error type (ex: (*MyError)(nil))
+// and error is matched with errors.As
+func RegisterErrorType(target error, mapping ErrorMapping) {
+	errorMappingMu.Lock()
+	defer errorMappingMu.Unlock()
+	errorMappings = append([]errorMappingEntry{{errType: reflect.TypeOf(target), mapping: mapping}}, errorMappings...)
+}
+
+// LookupErrorMapping get registered mapping of error, return DefaultErrorMapping if error is not registered
+func LookupErrorMapping(err error) ErrorMapping {
+	errorMappingMu.RLock()
+	defer errorMappingMu.RUnlock()
+
+	for _, entry := range errorMappings {
+		if entry.target != nil && errors.Is(err, entry.target) {
+			return entry.mapping
+		}
+		if entry.errType != nil && errors.As(err, reflect.New(entry.errType).Interface()) {
+			return entry.mapping
+		}
+	}
+	return DefaultErrorMapping
+}
+
+// NewHTTPErrorResponse create response from registered mapping of error (see RegisterError and RegisterErrorType),
+// error is logged with level of the mapping. Use NewHTTPErrorResponseWithContext to mark trace span and translate message
+func NewHTTPErrorResponse(err error) HTTPResponse {
+	return NewHTTPErrorResponseWithContext(context.Background(), err)
+}
+
+// NewHTTPErrorResponseWithContext create response from registered mapping of error (see RegisterError and RegisterErrorType)
+// with message translated to locale in context (see LocaleMiddleware),
+// error is logged with level of the mapping and trace span in context is marked as error
+func NewHTTPErrorResponseWithContext(ctx context.Context, err error) HTTPResponse {
+	if err == nil {
+		return NewHTTPResponseV2(http.StatusOK, "success")
+	}
+
+	var locale string
+	if ctx != nil {
+		locale = LocaleFromContext(ctx)
+	}
+
+	mapping := LookupErrorMapping(err)
+	message := err.Error()
+	if mapping.MessageKey != "" {
+		message = T(locale, mapping.MessageKey)
+	}
+
+	var params []interface{}
+	var multiError *MultiError
+	if errors.As(err, &multiError) {
+		params = append(params, multiError.Localize(locale))
+	}
+
+	DefaultLogger.Log(ctx, mapping.LogLevel, err.Error(), map[string]interface{}{
+		"context": "http_error_response",
+		"scope":   message,
+		"status":  mapping.Status,
+	})
+	if TraceErrorFunc != nil && ctx != nil {
+		TraceErrorFunc(ctx, err)
+	}
+
+	resp := NewHTTPResponseV2(mapping.Status, message, params...).(*ResponseV2)
+	if mapping.MessageKey != "" {
+		resp.messageKey = mapping.MessageKey
+	}
+	return resp
+}
@@ -0,0 +1,93 @@
+package golib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPaymentError struct {
+	Reason string
+}
+
+func (e *testPaymentError) Error() string {
+	return "payment failed: " + e.Reason
+}
+
+func TestNewHTTPErrorResponse(t *testing.T) {
+	errInsufficientStock := errors.New("insufficient stock")
+	RegisterError(errInsufficientStock, ErrorMapping{Status: http.StatusUnprocessableEntity, MessageKey: "stock.insufficient", LogLevel: InfoLevel})
+	RegisterErrorType((*testPaymentError)(nil), ErrorMapping{Status: http.StatusPaymentRequired, LogLevel: WarnLevel})
+
+	multiError := NewMultiError()
+	multiError.Append("email", ErrRequired)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantMsg    string
+		wantErrors interface{}
+	}{
+		{name: "Testcase #1: Positive, not found", err: ErrNotFound, wantCode: http.StatusNotFound, wantMsg: "data not found"},
+		{name: "Testcase #2: Positive, wrapped conflict", err: fmt.Errorf("insert order: %w", ErrConflict), wantCode: http.StatusConflict, wantMsg: "data conflict"},
+		{name: "Testcase #3: Positive, context deadline", err: context.DeadlineExceeded, wantCode: http.StatusGatewayTimeout, wantMsg: "timeout"},
+		{name: "Testcase #4: Positive, validation error", err: fmt.Errorf("bind: %w", multiError), wantCode: http.StatusBadRequest, wantMsg: "validation error", wantErrors: map[string]string{"email": "is required"}},
+		{name: "Testcase #5: Positive, registered sentinel", err: errInsufficientStock, wantCode: http.StatusUnprocessableEntity, wantMsg: "stock.insufficient"},
+		{name: "Testcase #6: Positive, registered type use error message", err: &testPaymentError{Reason: "card declined"}, wantCode: http.StatusPaymentRequired, wantMsg: "payment failed: card declined"},
+		{name: "Testcase #7: Negative, unregistered error", err: errors.New("connection reset"), wantCode: http.StatusInternalServerError, wantMsg: "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var traced error
+			TraceErrorFunc = func(ctx context.Context, err error) { traced = err }
+			defer func() { TraceErrorFunc = nil }()
+
+			resp := NewHTTPErrorResponse(tt.err).(*ResponseV2)
+			assert.False(t, resp.Success)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Equal(t, tt.wantMsg, resp.Message)
+			assert.Equal(t, tt.wantErrors, resp.Errors)
+			assert.Equal(t, tt.err, traced)
+		})
+	}
+
+	t.Run("Testcase #8: Positive, nil error", func(t *testing.T) {
+		resp := NewHTTPErrorResponse(nil).(*ResponseV2)
+		assert.True(t, resp.Success)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+}
+
+func TestNewHTTPErrorResponseWithContext(t *testing.T) {
+	multiError := NewMultiError()
+	multiError.AppendCode("email", ErrorCodeRequired, ErrRequired)
+	ctx := WithLocale(context.Background(), "id-ID")
+
+	t.Run("Testcase #1: Positive, message in locale of context", func(t *testing.T) {
+		resp := NewHTTPErrorResponseWithContext(ctx, ErrNotFound).(*ResponseV2)
+		assert.Equal(t, ErrorDataNotFound, resp.Message)
+	})
+	t.Run("Testcase #2: Positive, validation error in locale of context", func(t *testing.T) {
+		resp := NewHTTPErrorResponseWithContext(ctx, multiError).(*ResponseV2)
+		assert.Equal(t, "validasi gagal", resp.Message)
+		assert.Equal(t, map[string]string{"email": "wajib diisi"}, resp.Errors)
+	})
+}
+
+func TestLookupErrorMapping(t *testing.T) {
+	errLocked := errors.New("account locked")
+	RegisterError(errLocked, ErrorMapping{Status: http.StatusForbidden, LogLevel: WarnLevel})
+	RegisterError(errLocked, ErrorMapping{Status: http.StatusLocked, LogLevel: WarnLevel})
+
+	t.Run("Testcase #1: Positive, latest registration take precedence", func(t *testing.T) {
+		assert.Equal(t, http.StatusLocked, LookupErrorMapping(errLocked).Status)
+	})
+	t.Run("Testcase #2: Negative, unregistered error use default", func(t *testing.T) {
+		assert.Equal(t, DefaultErrorMapping, LookupErrorMapping(errors.New("unknown")))
+	})
+}
@@ -1,8 +1,10 @@
 package golib
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"net/http"
 	"reflect"
 )
@@ -13,6 +15,23 @@ type HTTPResponse interface {
 	XML(w http.ResponseWriter) error
 }
 
+// HTTPResponseWriter response which is written with content type negotiated from request,
+// implemented by *ResponseV2 and *ProblemDetails (see WriteHTTPResponse)
+type HTTPResponseWriter interface {
+	Write(w http.ResponseWriter, req *http.Request) error
+}
+
+// multiErrorType type of MultiError value, it is normalised to *MultiError by NewHTTPResponseV2
+var multiErrorType = reflect.TypeOf((*MultiError)(nil)).Elem()
+
+// WriteHTTPResponse write response with Write if it implement HTTPResponseWriter, JSON otherwise
+func WriteHTTPResponse(w http.ResponseWriter, req *http.Request, resp HTTPResponse) error {
+	if writer, ok := resp.(HTTPResponseWriter); ok {
+		return writer.Write(w, req)
+	}
+	return resp.JSON(w)
+}
+
 type (
 	// ResponseV2 model
 	ResponseV2 struct {
@@ -23,6 +42,12 @@ type (
 		Data    interface{} `json:"data,omitempty"`
 		Include interface{} `json:"include,omitempty"`
 		Errors  interface{} `json:"errors,omitempty"`
+
+		cache *CacheOptions
+		// messageKey catalog key of Message, empty if Message is free text which isn't translated
+		messageKey string
+		// multiError source of Errors, kept with error codes and params so Write can translate it
+		multiError *MultiError
 	}
 
 	// Meta model
@@ -39,6 +64,20 @@ func NewHTTPResponseV2(code int, message string, params ...interface{}) HTTPResp
 	commonResponse := new(ResponseV2)
 
 	for _, param := range params {
+		if refValue := reflect.ValueOf(param); refValue.IsValid() && refValue.Type() == multiErrorType {
+			// copy MultiError passed by value through reflect, so its mutex isn't copied by assignment
+			normalised := reflect.New(multiErrorType)
+			normalised.Elem().Set(refValue)
+			param = normalised.Interface()
+		}
+		if multiError, ok := param.(*MultiError); ok {
+			if multiError != nil {
+				commonResponse.Errors = multiError.ToMap()
+				commonResponse.multiError = multiError
+			}
+			continue
+		}
+
 		// get value param if type is pointer
 		refValue := reflect.ValueOf(param)
 		if refValue.Kind() == reflect.Ptr {
@@ -49,8 +88,6 @@ func NewHTTPResponseV2(code int, message string, params ...interface{}) HTTPResp
 		switch val := param.(type) {
 		case Meta:
 			commonResponse.Meta = val
-		case MultiError:
-			commonResponse.Errors = val.ToMap()
 		case []interface{}:
 			commonResponse.Include = val
 		default:
@@ -65,6 +102,7 @@ func NewHTTPResponseV2(code int, message string, params ...interface{}) HTTPResp
 	}
 	commonResponse.Code = code
 	commonResponse.Message = message
+	commonResponse.messageKey = messageKeyOf(message)
 	return commonResponse
 }
 
@@ -74,6 +112,9 @@ func (resp *ResponseV2) JSON(w http.ResponseWriter) error {
 		resp.Data = struct{}{}
 	}
 	w.Header().Set("Content-Type", "application/json")
+	if resp.cache != nil {
+		return resp.writeCached(w, nil, func(buf *bytes.Buffer) error { return json.NewEncoder(buf).Encode(resp) })
+	}
 	w.WriteHeader(resp.Code)
 	return json.NewEncoder(w).Encode(resp)
 }
@@ -84,6 +125,43 @@ func (resp *ResponseV2) XML(w http.ResponseWriter) error {
 		resp.Data = struct{}{}
 	}
 	w.Header().Set("Content-Type", "application/xml")
+	if resp.cache != nil {
+		return resp.writeCached(w, nil, func(buf *bytes.Buffer) error { return xml.NewEncoder(buf).Encode(resp) })
+	}
 	w.WriteHeader(resp.Code)
 	return xml.NewEncoder(w).Encode(resp)
 }
+
+// Write for set http response with content type negotiated from Accept header of request (see RegisterResponseEncoder)
+// and message translated to locale of request (see LocaleFromRequest), response is translated on copy so it can be written again in other locale.
+// Response 406 Not Acceptable (in JSON) if no registered encoder match the Accept header
+func (resp *ResponseV2) Write(w http.ResponseWriter, req *http.Request) error {
+	localized := *resp
+	resp = &localized
+	if resp.Data == nil {
+		resp.Data = struct{}{}
+	}
+	resp.Localize(LocaleFromRequest(req))
+
+	accept := req.Header.Get("Accept")
+	contentType, encoder := getResponseEncoder(accept)
+	w.Header().Add("Vary", "Accept")
+	if encoder == nil {
+		notAcceptable := NewHTTPResponseV2(http.StatusNotAcceptable, fmt.Sprintf("cannot produce response for Accept: %s", accept))
+		return notAcceptable.JSON(w)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if resp.cache != nil {
+		return resp.writeCached(w, req, func(buf *bytes.Buffer) error { return encoder(buf, resp) })
+	}
+
+	buf := new(bytes.Buffer)
+	if err := encoder(buf, resp); err != nil {
+		w.Header().Del("Content-Type")
+		return err
+	}
+	w.WriteHeader(resp.Code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
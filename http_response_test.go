@@ -1,14 +1,21 @@
 package golib
 
 import (
+	"encoding/xml"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
 // writer implement http.ResponseWriter
 type writer struct {
 	http.ResponseWriter
@@ -125,6 +132,37 @@ func TestNewHTTPResponseV2(t *testing.T) {
 				Code:    400,
 				Message: "id cannot be empty",
 				Errors:  map[string]string{"test": "error test"},
+
+				multiError: multiError,
+			},
+		},
+		{
+			name: "Testcase #6: Response failed with multierror value",
+			args: args{
+				code:    http.StatusBadRequest,
+				message: "id cannot be empty",
+				params:  []interface{}{reflect.ValueOf(multiError).Elem().Interface()},
+			},
+			want: &ResponseV2{
+				Success: false,
+				Code:    400,
+				Message: "id cannot be empty",
+				Errors:  map[string]string{"test": "error test"},
+
+				multiError: multiError,
+			},
+		},
+		{
+			name: "Testcase #7: Response failed with nil multierror",
+			args: args{
+				code:    http.StatusBadRequest,
+				message: "id cannot be empty",
+				params:  []interface{}{(*MultiError)(nil)},
+			},
+			want: &ResponseV2{
+				Success: false,
+				Code:    400,
+				Message: "id cannot be empty",
 			},
 		},
 	}
@@ -149,3 +187,130 @@ func TestHTTPResponseXML(t *testing.T) {
 	w := new(writer)
 	assert.NoError(t, resp.XML(w))
 }
+
+// jsonOnlyResponse HTTPResponse which doesn't implement HTTPResponseWriter
+type jsonOnlyResponse struct{ resp *ResponseV2 }
+
+func (r jsonOnlyResponse) JSON(w http.ResponseWriter) error { return r.resp.JSON(w) }
+func (r jsonOnlyResponse) XML(w http.ResponseWriter) error  { return r.resp.XML(w) }
+
+func TestWriteHTTPResponse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/xml")
+
+	t.Run("Testcase #1: Positive, negotiated with HTTPResponseWriter", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.NoError(t, WriteHTTPResponse(w, req, NewHTTPResponseV2(http.StatusOK, "success")))
+		assert.Equal(t, XMLContentType, w.Header().Get("Content-Type"))
+	})
+	t.Run("Testcase #2: Positive, JSON without HTTPResponseWriter", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.NoError(t, WriteHTTPResponse(w, req, jsonOnlyResponse{&ResponseV2{Code: http.StatusOK}}))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestHTTPResponseXMLGolden(t *testing.T) {
+	multiError := NewMultiError()
+	multiError.AppendCode("name", ErrorCodeRequired, ErrRequired)
+	multiError.Append("items.0.sku", fmt.Errorf("invalid sku"))
+	multiError.Append("items.0.sku", fmt.Errorf("sku <not> found"))
+
+	tests := []struct {
+		name, golden string
+		resp         HTTPResponse
+	}{
+		{
+			name:   "Testcase #1: Success response with map data",
+			golden: "response_success.xml",
+			resp: NewHTTPResponseV2(http.StatusOK, "Get detail data", map[string]interface{}{
+				"orderId": "061499700037", "items": []map[string]interface{}{{"sku": "SKU1", "qty": 2}},
+				"isPaid": true, "note": nil,
+			}),
+		},
+		{
+			name:   "Testcase #2: Paginated response with include",
+			golden: "response_paginated.xml",
+			resp: NewHTTPResponseV2(http.StatusOK, "Fetch all data",
+				[]ExampleModel{{OrderID: "061499700032"}, {OrderID: "061499700033"}},
+				[]interface{}{map[string]string{"customer": "agungdp"}},
+				Meta{Page: 1, Limit: 2, TotalPages: 5, TotalRecords: 10},
+			),
+		},
+		{
+			name:   "Testcase #3: Validation error response",
+			golden: "response_validation_error.xml",
+			resp:   NewHTTPResponseV2(http.StatusBadRequest, "invalid payload", multiError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			assert.NoError(t, tt.resp.XML(w))
+			assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *updateGolden {
+				assert.NoError(t, ioutil.WriteFile(golden, w.Body.Bytes(), 0644))
+			}
+			want, err := ioutil.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), w.Body.String())
+
+			var doc struct {
+				XMLName xml.Name `xml:"response"`
+				Code    int      `xml:"code"`
+			}
+			assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &doc))
+		})
+	}
+}
+
+func TestHTTPResponseXMLElementNames(t *testing.T) {
+	defer func(names XMLElementNames) { ResponseXMLNames = names }(ResponseXMLNames)
+	ResponseXMLNames.Root = "result"
+	ResponseXMLNames.Data = "payload"
+	ResponseXMLNames.Item = "row"
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, NewHTTPResponseV2(http.StatusOK, "success", []string{"a"}).XML(w))
+	assert.Equal(t, `<result><success>true</success><code>200</code><message>success</message>`+
+		`<payload><row>a</row></payload></result>`, w.Body.String())
+}
+
+// xmlOrder order with xml tag and attribute
+type xmlOrder struct {
+	ID    string `xml:"id,attr"`
+	Total int    `xml:"grand-total"`
+}
+
+// xmlMoney money with custom XML encoding
+type xmlMoney int
+
+func (m *xmlMoney) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(fmt.Sprintf("IDR %d", int(*m)), start)
+}
+
+func TestHTTPResponseXMLNative(t *testing.T) {
+	tests := []struct {
+		name, want string
+		data       interface{}
+	}{
+		{name: "Testcase #1: Positive, struct with xml tag", data: xmlOrder{ID: "SO1", Total: 10},
+			want: `<data id="SO1"><grand-total>10</grand-total></data>`},
+		{name: "Testcase #2: Positive, slice of struct with xml tag", data: []*xmlOrder{{ID: "SO1"}, {ID: "SO2"}},
+			want: `<data><item id="SO1"><grand-total>0</grand-total></item><item id="SO2"><grand-total>0</grand-total></item></data>`},
+		{name: "Testcase #3: Positive, xml.Marshaler with pointer receiver", data: xmlMoney(5000),
+			want: `<data>IDR 5000</data>`},
+		{name: "Testcase #4: Negative, struct without xml tag use JSON key", data: ExampleModel{OrderID: "SO1"},
+			want: `<data><orderId>SO1</orderId></data>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			assert.NoError(t, NewHTTPResponseV2(http.StatusOK, "success", tt.data).XML(w))
+			assert.Equal(t, `<response><success>true</success><code>200</code><message>success</message>`+
+				tt.want+`</response>`, w.Body.String())
+		})
+	}
+}
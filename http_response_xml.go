@@ -0,0 +1,201 @@
+package golib
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// XMLElementNames element name used in XML response of ResponseV2
+type XMLElementNames struct {
+	Root    string
+	Meta    string
+	Data    string
+	Include string
+	Errors  string
+	// Item element name of array item
+	Item string
+	// Entry element name of map entry which key is not valid XML name (key is set in "key" attribute)
+	Entry string
+}
+
+var (
+	// ResponseXMLNames element name of XML response, can be changed to follow client contract
+	ResponseXMLNames = XMLElementNames{
+		Root:    "response",
+		Meta:    "meta",
+		Data:    "data",
+		Include: "include",
+		Errors:  "errors",
+		Item:    "item",
+		Entry:   "entry",
+	}
+
+	xmlNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+	xmlMarshalerType = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+)
+
+// MarshalXML implement xml.Marshaler, value in meta, data, include and errors which implements xml.Marshaler
+// or is struct with xml tag (or slice of them) is encoded by encoding/xml. Other map and struct is converted to element
+// with key from JSON encoding (ex: map[string]string{"name": "is required"} to <name>is required</name>)
+func (resp *ResponseV2) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	names := ResponseXMLNames
+	start = xml.StartElement{Name: xml.Name{Local: names.Root}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, field := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"success", resp.Success},
+		{"code", resp.Code},
+		{"message", resp.Message},
+		{names.Meta, resp.Meta},
+		{names.Data, resp.Data},
+		{names.Include, resp.Include},
+		{names.Errors, resp.Errors},
+	} {
+		if field.value == nil {
+			continue
+		}
+
+		fieldStart := xml.StartElement{Name: xml.Name{Local: field.name}}
+		if v, ok := xmlNativeValue(field.value); ok {
+			if err := encodeNativeXMLElement(e, fieldStart, v, names); err != nil {
+				return err
+			}
+			continue
+		}
+
+		generic, err := toGenericValue(field.value)
+		if err != nil {
+			return err
+		}
+		if err := encodeXMLElement(e, fieldStart, generic, names); err != nil {
+			return err
+		}
+	}
+
+	if err := e.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// encodeXMLElement encode generic JSON value (see toGenericValue) as XML element
+func encodeXMLElement(e *xml.Encoder, start xml.StartElement, v interface{}, names XMLElementNames) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	var err error
+	switch val := v.(type) {
+	case nil:
+	case string:
+		err = e.EncodeToken(xml.CharData(val))
+	case bool:
+		err = e.EncodeToken(xml.CharData(strconv.FormatBool(val)))
+	case json.Number:
+		err = e.EncodeToken(xml.CharData(val.String()))
+	case []interface{}:
+		for _, item := range val {
+			if err = encodeXMLElement(e, xml.StartElement{Name: xml.Name{Local: names.Item}}, item, names); err != nil {
+				break
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := xml.StartElement{Name: xml.Name{Local: key}}
+			if !xmlNameRegexp.MatchString(key) {
+				child = xml.StartElement{
+					Name: xml.Name{Local: names.Entry},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+				}
+			}
+			if err = encodeXMLElement(e, child, val[key], names); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// xmlNativeValue value which is encoded by encoding/xml: it implements xml.Marshaler or it is struct with xml tag,
+// or slice or array of them. Value with pointer receiver MarshalXML is returned as pointer so the method is used
+func xmlNativeValue(v interface{}) (reflect.Value, bool) {
+	val := reflect.ValueOf(v)
+	if !xmlNativeType(val.Type()) {
+		return val, false
+	}
+	if val.Kind() != reflect.Ptr && reflect.PtrTo(val.Type()).Implements(xmlMarshalerType) {
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		val = ptr
+	}
+	return val, true
+}
+
+func xmlNativeType(t reflect.Type) bool {
+	if t.Implements(xmlMarshalerType) || reflect.PtrTo(t).Implements(xmlMarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		return xmlNativeType(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if _, ok := field.Tag.Lookup("xml"); ok || field.Name == "XMLName" {
+				return true
+			}
+			if field.Anonymous && xmlNativeType(field.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeNativeXMLElement encode value with encoding/xml, item of slice or array is wrapped in Item element
+func encodeNativeXMLElement(e *xml.Encoder, start xml.StartElement, v reflect.Value, names XMLElementNames) error {
+	for v.Kind() == reflect.Ptr && !v.Type().Implements(xmlMarshalerType) {
+		if v.IsNil() {
+			return e.EncodeElement(nil, start)
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Implements(xmlMarshalerType) {
+		return e.EncodeElement(v.Interface(), start)
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		item, _ := xmlNativeValue(v.Index(i).Interface())
+		if err := encodeNativeXMLElement(e, xml.StartElement{Name: xml.Name{Local: names.Item}}, item, names); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
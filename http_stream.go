@@ -0,0 +1,263 @@
+package golib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// StreamFormat format of streaming response
+type StreamFormat int
+
+const (
+	// StreamJSONArray stream data item in JSON array of envelope (Content-Type: application/json),
+	// ex: {"success":true,"code":200,"message":"...","meta":{...},"data":[item1,item2]}
+	StreamJSONArray StreamFormat = iota
+	// StreamNDJSON stream envelope header in first line and one data item per line (Content-Type: application/x-ndjson)
+	StreamNDJSON
+	// StreamSSE stream envelope header in "header" event and each data item in "data" event (Content-Type: text/event-stream)
+	StreamSSE
+)
+
+const (
+	// NDJSONContentType media type of newline delimited JSON
+	NDJSONContentType = "application/x-ndjson"
+	// EventStreamContentType media type of Server-Sent Events
+	EventStreamContentType = "text/event-stream"
+)
+
+// StreamIterator return next data item of stream, ok is false when there is no more item
+type StreamIterator func() (item interface{}, ok bool, err error)
+
+// StreamError stop stream with Err when it is received from channel of Stream,
+// other value (even if it implements error) is streamed as data item
+type StreamError struct {
+	Err error
+}
+
+func (e *StreamError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap return the wrapped error
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// StreamWriter write envelope header (success, code, message and meta) then stream data items,
+// response is flushed after each item and stream is stopped when request context is done
+type StreamWriter struct {
+	Format  StreamFormat
+	Success bool
+	Code    int
+	Message string
+	Meta    interface{}
+	// HeartbeatInterval interval of heartbeat comment in SSE format, heartbeat is disabled if zero
+	HeartbeatInterval time.Duration
+}
+
+// NewStreamWriter constructor, success is set if code less than 400 and meta is optional
+func NewStreamWriter(format StreamFormat, code int, message string, meta ...interface{}) *StreamWriter {
+	s := &StreamWriter{
+		Format:            format,
+		Success:           code < http.StatusBadRequest,
+		Code:              code,
+		Message:           message,
+		HeartbeatInterval: 15 * time.Second,
+	}
+	if len(meta) > 0 {
+		s.Meta = meta[0]
+	}
+	return s
+}
+
+// Stream write data item received from channel until the channel is closed, *StreamError received from channel
+// stop the stream and its Err is returned
+func (s *StreamWriter) Stream(w http.ResponseWriter, req *http.Request, items <-chan interface{}) error {
+	if err := s.writeHeader(w); err != nil {
+		return err
+	}
+
+	var heartbeat <-chan time.Time
+	if s.Format == StreamSSE && s.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(s.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	var count int
+	for {
+		select {
+		case <-req.Context().Done():
+			return req.Context().Err()
+
+		case <-heartbeat:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return err
+			}
+			flushResponse(w)
+
+		case item, ok := <-items:
+			if !ok {
+				return s.writeEnd(w, count, nil)
+			}
+			if streamErr, isErr := item.(*StreamError); isErr {
+				s.writeEnd(w, count, streamErr.Err)
+				return streamErr.Err
+			}
+			if err := s.writeItem(w, count, item); err != nil {
+				return err
+			}
+			count++
+		}
+	}
+}
+
+// StreamIterator write data item returned by iterator until there is no more item, iterator error stop the stream.
+// Iterator isn't called again once request context is done, and it is no longer running when StreamIterator returns
+func (s *StreamWriter) StreamIterator(w http.ResponseWriter, req *http.Request, next StreamIterator) error {
+	ctx := req.Context()
+	items := make(chan interface{})
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		defer close(items)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			item, ok, err := next()
+			if err != nil {
+				item, ok = &StreamError{Err: err}, true
+			}
+			if !ok {
+				return
+			}
+
+			select {
+			case items <- item:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	err := s.Stream(w, req, items)
+	close(done)
+	<-exited
+	return err
+}
+
+func (s *StreamWriter) writeHeader(w http.ResponseWriter) error {
+	header, err := json.Marshal(ResponseV2{Success: s.Success, Code: s.Code, Message: s.Message, Meta: s.Meta})
+	if err != nil {
+		return err
+	}
+
+	switch s.Format {
+	case StreamNDJSON:
+		w.Header().Set("Content-Type", NDJSONContentType)
+		header = append(header, '\n')
+	case StreamSSE:
+		w.Header().Set("Content-Type", EventStreamContentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		header = []byte(fmt.Sprintf("event: header\ndata: %s\n\n", header))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		// open data array in envelope object
+		header = append(bytes.TrimSuffix(header, []byte("}")), []byte(`,"data":[`)...)
+	}
+
+	w.WriteHeader(s.Code)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	flushResponse(w)
+	return nil
+}
+
+func (s *StreamWriter) writeItem(w http.ResponseWriter, index int, item interface{}) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	switch s.Format {
+	case StreamNDJSON:
+		buf.Write(b)
+		buf.WriteByte('\n')
+	case StreamSSE:
+		fmt.Fprintf(buf, "event: data\nid: %d\ndata: %s\n\n", index+1, b)
+	default:
+		if index > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	flushResponse(w)
+	return nil
+}
+
+// writeEnd close the stream, stream error is written in errors of envelope
+func (s *StreamWriter) writeEnd(w http.ResponseWriter, count int, streamErr error) error {
+	var end []byte
+	var errs []byte
+	if streamErr != nil {
+		errs, _ = json.Marshal(map[string]string{"stream": streamErr.Error()})
+	}
+
+	switch s.Format {
+	case StreamNDJSON:
+		if streamErr != nil {
+			end, _ = json.Marshal(ResponseV2{Success: false, Code: http.StatusInternalServerError, Message: streamErr.Error()})
+			end = append(end, '\n')
+		}
+	case StreamSSE:
+		if streamErr != nil {
+			end = []byte(fmt.Sprintf("event: error\ndata: %s\n\n", errs))
+		} else {
+			end = []byte(fmt.Sprintf("event: end\ndata: {\"count\":%d}\n\n", count))
+		}
+	default:
+		end = []byte("]")
+		if streamErr != nil {
+			end = append(end, []byte(`,"errors":`)...)
+			end = append(end, errs...)
+		}
+		end = append(end, []byte("}\n")...)
+	}
+
+	if len(end) == 0 {
+		return nil
+	}
+	if _, err := w.Write(end); err != nil {
+		return err
+	}
+	flushResponse(w)
+	return nil
+}
+
+func flushResponse(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
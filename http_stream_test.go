@@ -0,0 +1,176 @@
+package golib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func streamItems(items ...interface{}) <-chan interface{} {
+	ch := make(chan interface{}, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
+
+func TestStreamWriter(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+	meta := Meta{Page: 1, Limit: 10, TotalRecords: 2, TotalPages: 1}
+
+	tests := []struct {
+		name, contentType, want string
+		format                  StreamFormat
+		items                   []interface{}
+		wantErr                 bool
+	}{
+		{
+			name: "Testcase #1: Positive, chunked json array", format: StreamJSONArray, contentType: "application/json",
+			items: []interface{}{item{1}, item{2}},
+			want:  `{"success":true,"code":200,"message":"ok","meta":{"page":1,"limit":10,"totalRecords":2,"totalPages":1},"data":[{"id":1},{"id":2}]}` + "\n",
+		},
+		{
+			name: "Testcase #2: Positive, ndjson", format: StreamNDJSON, contentType: NDJSONContentType,
+			items: []interface{}{item{1}, item{2}},
+			want: `{"success":true,"code":200,"message":"ok","meta":{"page":1,"limit":10,"totalRecords":2,"totalPages":1}}` + "\n" +
+				`{"id":1}` + "\n" + `{"id":2}` + "\n",
+		},
+		{
+			name: "Testcase #3: Positive, server-sent events", format: StreamSSE, contentType: EventStreamContentType,
+			items: []interface{}{item{1}},
+			want: "event: header\ndata: {\"success\":true,\"code\":200,\"message\":\"ok\",\"meta\":{\"page\":1,\"limit\":10,\"totalRecords\":2,\"totalPages\":1}}\n\n" +
+				"event: data\nid: 1\ndata: {\"id\":1}\n\n" +
+				"event: end\ndata: {\"count\":1}\n\n",
+		},
+		{
+			name: "Testcase #4: Negative, error in json array", format: StreamJSONArray, contentType: "application/json",
+			items:   []interface{}{item{1}, &StreamError{Err: errors.New("database closed")}},
+			want:    `{"success":true,"code":200,"message":"ok","meta":{"page":1,"limit":10,"totalRecords":2,"totalPages":1},"data":[{"id":1}],"errors":{"stream":"database closed"}}` + "\n",
+			wantErr: true,
+		},
+		{
+			name: "Testcase #5: Negative, error in ndjson", format: StreamNDJSON, contentType: NDJSONContentType,
+			items: []interface{}{&StreamError{Err: errors.New("database closed")}},
+			want: `{"success":true,"code":200,"message":"ok","meta":{"page":1,"limit":10,"totalRecords":2,"totalPages":1}}` + "\n" +
+				`{"success":false,"code":500,"message":"database closed"}` + "\n",
+			wantErr: true,
+		},
+		{
+			name: "Testcase #6: Negative, error in server-sent events", format: StreamSSE, contentType: EventStreamContentType,
+			items: []interface{}{&StreamError{Err: errors.New("database closed")}},
+			want: "event: header\ndata: {\"success\":true,\"code\":200,\"message\":\"ok\",\"meta\":{\"page\":1,\"limit\":10,\"totalRecords\":2,\"totalPages\":1}}\n\n" +
+				"event: error\ndata: {\"stream\":\"database closed\"}\n\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+			err := NewStreamWriter(tt.format, http.StatusOK, "ok", meta).Stream(rec, req, streamItems(tt.items...))
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want, rec.Body.String())
+			assert.True(t, rec.Flushed)
+		})
+	}
+}
+
+func TestStreamWriterIterator(t *testing.T) {
+	t.Run("Testcase #1: Positive", func(t *testing.T) {
+		i := 0
+		next := func() (interface{}, bool, error) {
+			i++
+			return i, i <= 3, nil
+		}
+
+		rec := httptest.NewRecorder()
+		err := NewStreamWriter(StreamNDJSON, http.StatusOK, "ok").StreamIterator(rec, httptest.NewRequest(http.MethodGet, "/", nil), next)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"success\":true,\"code\":200,\"message\":\"ok\"}\n1\n2\n3\n", rec.Body.String())
+	})
+	t.Run("Testcase #2: Negative, iterator error", func(t *testing.T) {
+		next := func() (interface{}, bool, error) {
+			return nil, false, errors.New("cursor failed")
+		}
+
+		rec := httptest.NewRecorder()
+		err := NewStreamWriter(StreamJSONArray, http.StatusOK, "ok").StreamIterator(rec, httptest.NewRequest(http.MethodGet, "/", nil), next)
+		assert.EqualError(t, err, "cursor failed")
+		assert.Equal(t, "{\"success\":true,\"code\":200,\"message\":\"ok\",\"data\":[],\"errors\":{\"stream\":\"cursor failed\"}}\n", rec.Body.String())
+	})
+	t.Run("Testcase #3: Positive, data item implementing error is streamed", func(t *testing.T) {
+		items := []interface{}{&testPaymentError{Reason: "card declined"}}
+		next := func() (interface{}, bool, error) {
+			if len(items) == 0 {
+				return nil, false, nil
+			}
+			item := items[0]
+			items = items[1:]
+			return item, true, nil
+		}
+
+		rec := httptest.NewRecorder()
+		err := NewStreamWriter(StreamNDJSON, http.StatusOK, "ok").StreamIterator(rec, httptest.NewRequest(http.MethodGet, "/", nil), next)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"success\":true,\"code\":200,\"message\":\"ok\"}\n{\"Reason\":\"card declined\"}\n", rec.Body.String())
+	})
+}
+
+func TestStreamWriterCancel(t *testing.T) {
+	t.Run("Testcase #1: Positive, heartbeat until request context done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+
+		s := NewStreamWriter(StreamSSE, http.StatusOK, "ok")
+		s.HeartbeatInterval = 10 * time.Millisecond
+		err := s.Stream(rec, req, make(chan interface{}))
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.True(t, strings.Contains(rec.Body.String(), ": heartbeat\n\n"))
+		assert.False(t, strings.Contains(rec.Body.String(), "event: end"))
+	})
+	t.Run("Testcase #2: Positive, iterator stopped when request context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+		calls := 0
+		next := func() (interface{}, bool, error) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+			return calls, true, nil
+		}
+		err := NewStreamWriter(StreamNDJSON, http.StatusOK, "ok").StreamIterator(httptest.NewRecorder(), req, next)
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("Testcase #3: Positive, blocked iterator is finished before return", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+		var finished bool
+		next := func() (interface{}, bool, error) {
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+			finished = true
+			return 1, true, nil
+		}
+		err := NewStreamWriter(StreamNDJSON, http.StatusOK, "ok").StreamIterator(httptest.NewRecorder(), req, next)
+		assert.Equal(t, context.Canceled, err)
+		assert.True(t, finished)
+	})
+}
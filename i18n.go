@@ -0,0 +1,675 @@
+package golib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultLocale fallback locale of DefaultCatalog
+const DefaultLocale = "en-US"
+
+// PluralCategory CLDR plural category
+type PluralCategory string
+
+// CLDR plural categories
+const (
+	PluralZero  PluralCategory = "zero"
+	PluralOne   PluralCategory = "one"
+	PluralTwo   PluralCategory = "two"
+	PluralFew   PluralCategory = "few"
+	PluralMany  PluralCategory = "many"
+	PluralOther PluralCategory = "other"
+)
+
+// PluralRule return plural category of count n
+type PluralRule func(n float64) PluralCategory
+
+var (
+	pluralMu    sync.RWMutex
+	pluralRules = map[string]PluralRule{}
+
+	// DefaultCatalog message catalog used by T, ResponseV2.Localize and MultiError.Localize,
+	// contain built-in messages of this library in en-US and id-ID
+	DefaultCatalog = NewCatalog(DefaultLocale)
+)
+
+func init() {
+	for _, lang := range []string{"id", "ms", "ja", "zh", "ko", "th", "vi", "lo", "km", "my"} {
+		RegisterPluralRule(lang, pluralRuleOther)
+	}
+	for _, lang := range []string{"en", "de", "nl", "sv", "it", "fi", "et", "ca"} {
+		RegisterPluralRule(lang, pluralRuleOneInteger)
+	}
+	for _, lang := range []string{"es", "el", "hu", "bg", "nb", "tr"} {
+		RegisterPluralRule(lang, pluralRuleOne)
+	}
+	for _, lang := range []string{"fr", "pt"} {
+		RegisterPluralRule(lang, pluralRuleZeroOne)
+	}
+	for _, lang := range []string{"ru", "uk"} {
+		RegisterPluralRule(lang, pluralRuleEastSlavic)
+	}
+	RegisterPluralRule("pl", pluralRulePolish)
+	RegisterPluralRule("cs", pluralRuleCzech)
+	RegisterPluralRule("sk", pluralRuleCzech)
+	RegisterPluralRule("ar", pluralRuleArabic)
+
+	for locale, messages := range builtinMessages {
+		if err := DefaultCatalog.AddMessages(locale, messages); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// builtinMessageKeys catalog key of built-in message constants, so response created with the constant
+// (ex: NewHTTPResponseV2(http.StatusNotFound, ErrorDataNotFound)) is translated by ResponseV2.Localize
+var builtinMessageKeys = map[string]string{
+	ErrorDataNotFound: "error.not_found",
+}
+
+// builtinMessages messages of this library, validation messages is only translated to id-ID
+// so original english message of validator and jsonschema is kept for en-US
+var builtinMessages = map[string]map[string]interface{}{
+	"en-US": {
+		"error": map[string]interface{}{
+			"not_found":              "data not found",
+			"conflict":               "data conflict",
+			"unauthorized":           "unauthorized",
+			"forbidden":              "forbidden",
+			"timeout":                "timeout",
+			"validation":             "validation error",
+			"body_too_large":         "request body too large",
+			"unsupported_media_type": "unsupported media type",
+			"internal":               "internal server error",
+		},
+	},
+	"id-ID": {
+		"error": map[string]interface{}{
+			"not_found":              ErrorDataNotFound,
+			"conflict":               "data konflik dengan data yang sudah ada",
+			"unauthorized":           "tidak terautentikasi",
+			"forbidden":              "akses ditolak",
+			"timeout":                "waktu proses habis",
+			"validation":             "validasi gagal",
+			"body_too_large":         "ukuran request terlalu besar",
+			"unsupported_media_type": "tipe media tidak didukung",
+			"internal":               "terjadi kesalahan pada server",
+		},
+		"validation": map[string]interface{}{
+			ErrorCodeRequired:      "wajib diisi",
+			ErrorCodeInvalidFormat: "format tidak valid",
+			ErrorCodeNotAllowed:    "tidak diizinkan",
+			"min":                  "minimal {param}",
+			"max":                  "maksimal {param}",
+			"oneof":                "harus salah satu dari {param}",
+			"email":                "format email tidak valid",
+			"url":                  "format url tidak valid",
+			"phone":                "format nomor telepon tidak valid",
+			"alphanum":             "hanya boleh berisi huruf dan angka",
+			"invalid_type":         "tipe harus {expected}, bukan {given}",
+			"string_gte":           "minimal {min} karakter",
+			"string_lte":           "maksimal {max} karakter",
+			"number_gte":           "minimal {min}",
+			"number_lte":           "maksimal {max}",
+			"enum":                 "harus salah satu dari {allowed}",
+			"format":               "format harus {format}",
+		},
+	},
+}
+
+// RegisterPluralRule register CLDR plural rule of language (ex: "en"), existing rule will be replaced
+func RegisterPluralRule(lang string, rule PluralRule) {
+	pluralMu.Lock()
+	defer pluralMu.Unlock()
+	pluralRules[strings.ToLower(lang)] = rule
+}
+
+// PluralCategoryOf return plural category of count n in locale,
+// rule of english is used if language of locale is not registered
+func PluralCategoryOf(locale string, n float64) PluralCategory {
+	pluralMu.RLock()
+	rule, ok := pluralRules[baseLanguage(canonicalLocale(locale))]
+	pluralMu.RUnlock()
+	if !ok {
+		rule = pluralRuleOneInteger
+	}
+	return rule(n)
+}
+
+// Catalog message catalog with plural forms and templated parameters (ex: "minimal {min} karakter"), safe for concurrent use
+type Catalog struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]map[PluralCategory]string
+}
+
+// NewCatalog constructor, message not found in requested locale is taken from fallback locale
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback: canonicalLocale(fallback),
+		messages: make(map[string]map[string]map[PluralCategory]string),
+	}
+}
+
+// Add add message of key in locale, existing message will be replaced
+func (c *Catalog) Add(locale, key, message string) {
+	c.AddPlural(locale, key, map[PluralCategory]string{PluralOther: message})
+}
+
+// AddPlural add message of key in locale with plural forms, form is selected by "count" param
+func (c *Catalog) AddPlural(locale, key string, forms map[PluralCategory]string) {
+	locale = canonicalLocale(locale)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]map[PluralCategory]string)
+	}
+	c.messages[locale][key] = forms
+}
+
+// AddMessages add messages of locale, nested object is flattened to dotted key (ex: {"error": {"timeout": "..."}} to "error.timeout")
+// and object with only plural category keys (zero, one, two, few, many, other) is added as plural forms
+func (c *Catalog) AddMessages(locale string, messages map[string]interface{}) error {
+	return c.addMessages(locale, "", messages)
+}
+
+func (c *Catalog) addMessages(locale, prefix string, messages map[string]interface{}) error {
+	for key, value := range messages {
+		key = prefix + key
+
+		switch val := toStringMap(value).(type) {
+		case string:
+			c.Add(locale, key, val)
+		case map[string]interface{}:
+			if forms, ok := toPluralForms(val); ok {
+				c.AddPlural(locale, key, forms)
+				continue
+			}
+			if err := c.addMessages(locale, key+".", val); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid message '%s': must string or object", key)
+		}
+	}
+	return nil
+}
+
+// LoadFile load messages from JSON (.json) or YAML (.yaml, .yml) file, locale is taken from file name (ex: "id-ID.yaml")
+func (c *Catalog) LoadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(path)
+	messages := make(map[string]interface{})
+	switch strings.ToLower(ext) {
+	case ".json":
+		err = json.Unmarshal(b, &messages)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &messages)
+	default:
+		return fmt.Errorf("unsupported message file '%s'", path)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot parse message file '%s': %v", path, err)
+	}
+
+	return c.AddMessages(strings.TrimSuffix(filepath.Base(path), ext), messages)
+}
+
+// LoadDir load all JSON and YAML message files in directory and its sub directories
+func (c *Catalog) LoadDir(path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".json", ".yaml", ".yml":
+			return c.LoadFile(p)
+		}
+		return nil
+	})
+}
+
+// Locales return sorted list of locale in catalog
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup find message of key in locale, its base language (ex: "id" for "id-ID") and then fallback locale.
+// Param "count" select the plural form and every "{name}" in message is replaced with param value
+func (c *Catalog) Lookup(locale, key string, params map[string]interface{}) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, candidate := range c.localeChain(locale) {
+		forms, ok := c.messages[candidate][key]
+		if !ok {
+			continue
+		}
+
+		message := forms[PluralOther]
+		if count, isNumber := toFloat(params["count"]); isNumber {
+			if form, ok := forms[PluralCategoryOf(candidate, count)]; ok {
+				message = form
+			}
+		}
+		return renderMessage(message, params), true
+	}
+	return "", false
+}
+
+// T translate key to locale, key is returned as is if it is not found in catalog
+func (c *Catalog) T(locale, key string, params ...map[string]interface{}) string {
+	merged := make(map[string]interface{})
+	for _, p := range params {
+		for k, v := range p {
+			merged[k] = v
+		}
+	}
+
+	if message, ok := c.Lookup(locale, key, merged); ok {
+		return message
+	}
+	return key
+}
+
+// Match return best locale in catalog for Accept-Language header (ex: "id-ID,id;q=0.9,en;q=0.8"),
+// fallback locale is returned if there is no match
+func (c *Catalog) Match(acceptLanguage string) string {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+
+	var ranges []languageRange
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, languageRange{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	locales := c.Locales()
+	has := make(map[string]bool, len(locales))
+	for _, locale := range locales {
+		has[locale] = true
+	}
+
+	for _, r := range ranges {
+		if r.tag == "*" {
+			return c.fallback
+		}
+
+		tag := canonicalLocale(r.tag)
+		if has[tag] {
+			return tag
+		}
+		base := baseLanguage(tag)
+		if has[base] {
+			return base
+		}
+		for _, locale := range locales {
+			if baseLanguage(locale) == base {
+				return locale
+			}
+		}
+	}
+	return c.fallback
+}
+
+// localeChain list of locale to lookup message
+func (c *Catalog) localeChain(locale string) []string {
+	var chain []string
+	exist := make(map[string]bool)
+	for _, l := range []string{locale, c.fallback} {
+		l = canonicalLocale(l)
+		for _, candidate := range []string{l, baseLanguage(l)} {
+			if candidate != "" && !exist[candidate] {
+				exist[candidate] = true
+				chain = append(chain, candidate)
+			}
+		}
+	}
+	return chain
+}
+
+// LocalizeErrors return copy of multierror with message of every error item translated to locale,
+// message is taken from key "validation.<code>" with error item params and "field" param,
+// error item without code or translation is kept as is
+func (c *Catalog) LocalizeErrors(locale string, m *MultiError) *MultiError {
+	if m == nil {
+		return nil
+	}
+
+	localized := NewMultiError()
+	for _, item := range m.Errors() {
+		copied := *item
+		if item.Code != "" {
+			params := map[string]interface{}{"field": item.Key}
+			for k, v := range item.Params {
+				params[k] = v
+			}
+			if message, ok := c.Lookup(locale, "validation."+item.Code, params); ok {
+				copied.Err = &localizedError{message: message, err: item.Err}
+			}
+		}
+		localized.AppendItem(&copied)
+	}
+	return localized
+}
+
+// T translate key to locale with DefaultCatalog
+func T(locale, key string, params ...map[string]interface{}) string {
+	return DefaultCatalog.T(locale, key, params...)
+}
+
+// Localize return copy of multierror translated to locale with DefaultCatalog (see Catalog.LocalizeErrors)
+func (m *MultiError) Localize(locale string) *MultiError {
+	return DefaultCatalog.LocalizeErrors(locale, m)
+}
+
+// Localize translate message of response to locale with DefaultCatalog, only message created from catalog key
+// (message key of ErrorMapping, key in DefaultCatalog or built-in message constant) is translated and free text is kept as is.
+// Errors from *MultiError param of NewHTTPResponseV2 are translated from their codes (see Catalog.LocalizeErrors), other errors are kept as is
+func (resp *ResponseV2) Localize(locale string) {
+	if resp.messageKey != "" {
+		resp.Message = T(locale, resp.messageKey)
+	}
+	if resp.multiError != nil {
+		resp.Errors = DefaultCatalog.LocalizeErrors(locale, resp.multiError).ToMap()
+	}
+}
+
+// messageKeyOf get catalog key of response message, empty if message is free text
+func messageKeyOf(message string) string {
+	if key, ok := builtinMessageKeys[message]; ok {
+		return key
+	}
+	if _, ok := DefaultCatalog.Lookup("", message, nil); ok {
+		return message
+	}
+	return ""
+}
+
+type localeContextKey struct{}
+
+// WithLocale return copy of context with locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// LocaleFromContext get locale from context, return empty string if not set
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeContextKey{}).(string)
+	return locale
+}
+
+// LocaleFromRequest get locale from request context (see LocaleMiddleware)
+// or best locale of DefaultCatalog for Accept-Language header
+func LocaleFromRequest(req *http.Request) string {
+	if locale := LocaleFromContext(req.Context()); locale != "" {
+		return locale
+	}
+	return DefaultCatalog.Match(req.Header.Get("Accept-Language"))
+}
+
+// LocaleMiddleware set locale from Accept-Language header to request context and Content-Language response header
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		locale := LocaleFromRequest(req)
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, req.WithContext(WithLocale(req.Context(), locale)))
+	})
+}
+
+// localizedError translated error message which keep original error in chain
+type localizedError struct {
+	message string
+	err     error
+}
+
+func (e *localizedError) Error() string {
+	return e.message
+}
+
+func (e *localizedError) Unwrap() error {
+	return e.err
+}
+
+// canonicalLocale format locale tag (ex: "id_id" to "id-ID", "zh-hant-tw" to "zh-Hant-TW")
+func canonicalLocale(tag string) string {
+	tag = strings.Replace(strings.TrimSpace(tag), "_", "-", -1)
+	if tag == "" {
+		return ""
+	}
+
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func baseLanguage(locale string) string {
+	return strings.SplitN(locale, "-", 2)[0]
+}
+
+// renderMessage replace every "{name}" in message with param value, unknown param is kept as is
+func renderMessage(message string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(message, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(message[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(message[:start])
+		if value, ok := params[message[start+1:end]]; ok {
+			b.WriteString(fmt.Sprint(value))
+		} else {
+			b.WriteString(message[start : end+1])
+		}
+		message = message[end+1:]
+	}
+	b.WriteString(message)
+	return b.String()
+}
+
+// toStringMap convert map of YAML decoder (map[interface{}]interface{}) to map[string]interface{}
+func toStringMap(value interface{}) interface{} {
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return value
+	}
+
+	converted := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		converted[fmt.Sprint(k)] = v
+	}
+	return converted
+}
+
+func toPluralForms(m map[string]interface{}) (map[PluralCategory]string, bool) {
+	forms := make(map[PluralCategory]string, len(m))
+	for k, v := range m {
+		switch category := PluralCategory(k); category {
+		case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+			s, ok := v.(string)
+			if !ok {
+				return nil, false
+			}
+			forms[category] = s
+		default:
+			return nil, false
+		}
+	}
+	_, hasOther := forms[PluralOther]
+	return forms, hasOther
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	refValue := reflect.ValueOf(value)
+	switch refValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(refValue.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(refValue.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return refValue.Float(), true
+	}
+	return 0, false
+}
+
+// plural rules from CLDR, i is integer digits of n and v is number of visible fraction digits (v = 0 if n is integer)
+
+func pluralRuleOther(n float64) PluralCategory {
+	return PluralOther
+}
+
+func pluralRuleOneInteger(n float64) PluralCategory {
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleOne(n float64) PluralCategory {
+	if math.Abs(n) == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleZeroOne(n float64) PluralCategory {
+	if i := math.Trunc(math.Abs(n)); i == 0 || i == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleEastSlavic(n float64) PluralCategory {
+	if n != math.Trunc(n) {
+		return PluralOther
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i%10 == 1 && i%100 != 11:
+		return PluralOne
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralRulePolish(n float64) PluralCategory {
+	if n != math.Trunc(n) {
+		return PluralOther
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i == 1:
+		return PluralOne
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralRuleCzech(n float64) PluralCategory {
+	if n != math.Trunc(n) {
+		return PluralMany
+	}
+	switch i := int64(math.Abs(n)); {
+	case i == 1:
+		return PluralOne
+	case i >= 2 && i <= 4:
+		return PluralFew
+	default:
+		return PluralOther
+	}
+}
+
+func pluralRuleArabic(n float64) PluralCategory {
+	if n != math.Trunc(n) {
+		return PluralOther
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i == 0:
+		return PluralZero
+	case i == 1:
+		return PluralOne
+	case i == 2:
+		return PluralTwo
+	case i%100 >= 3 && i%100 <= 10:
+		return PluralFew
+	case i%100 >= 11 && i%100 <= 99:
+		return PluralMany
+	default:
+		return PluralOther
+	}
+}
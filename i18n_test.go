@@ -0,0 +1,173 @@
+package golib
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog := NewCatalog("en-US")
+	assert.NoError(t, catalog.LoadDir("testdata/i18n"))
+	assert.Equal(t, []string{"en-US", "id-ID", "ru"}, catalog.Locales())
+
+	tests := []struct {
+		name, locale, key, want string
+		params                  map[string]interface{}
+	}{
+		{name: "Testcase #1: Positive, templated param", locale: "id-ID", key: "order.created", params: map[string]interface{}{"orderId": "SO-1"}, want: "pesanan SO-1 berhasil dibuat"},
+		{name: "Testcase #2: Positive, plural one", locale: "en-US", key: "order.items", params: map[string]interface{}{"count": 1}, want: "1 item in cart"},
+		{name: "Testcase #3: Positive, plural other", locale: "en-US", key: "order.items", params: map[string]interface{}{"count": 3}, want: "3 items in cart"},
+		{name: "Testcase #4: Positive, language without plural", locale: "id-ID", key: "order.items", params: map[string]interface{}{"count": 1}, want: "1 barang di keranjang"},
+		{name: "Testcase #5: Positive, plural few", locale: "ru-RU", key: "order.items", params: map[string]interface{}{"count": 22}, want: "22 товара"},
+		{name: "Testcase #6: Positive, plural many", locale: "ru", key: "order.items", params: map[string]interface{}{"count": 11}, want: "11 товаров"},
+		{name: "Testcase #7: Positive, fallback locale", locale: "fr-FR", key: "order.created", params: map[string]interface{}{"orderId": "SO-2"}, want: "order SO-2 created"},
+		{name: "Testcase #8: Positive, case insensitive locale", locale: "id_id", key: "order.created", want: "pesanan {orderId} berhasil dibuat"},
+		{name: "Testcase #9: Negative, unknown key", locale: "id-ID", key: "order.deleted", want: "order.deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, catalog.T(tt.locale, tt.key, tt.params))
+		})
+	}
+
+	t.Run("Testcase #10: Negative, invalid message file", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "i18n")
+		defer os.RemoveAll(dir)
+
+		ioutil.WriteFile(filepath.Join(dir, "en-US.json"), []byte(`{"order": {"total": 10}}`), 0644)
+		assert.EqualError(t, NewCatalog("en-US").LoadDir(dir), "invalid message 'order.total': must string or object")
+
+		ioutil.WriteFile(filepath.Join(dir, "en-US.json"), []byte(`{"order":`), 0644)
+		assert.Error(t, NewCatalog("en-US").LoadDir(dir))
+	})
+}
+
+func TestCatalogMatch(t *testing.T) {
+	catalog := NewCatalog("en-US")
+	catalog.Add("en-US", "hello", "hello")
+	catalog.Add("id-ID", "hello", "halo")
+	catalog.Add("pt-BR", "hello", "olá")
+
+	tests := []struct {
+		name, accept, want string
+	}{
+		{name: "Testcase #1: Positive, exact", accept: "id-ID", want: "id-ID"},
+		{name: "Testcase #2: Positive, base language", accept: "id", want: "id-ID"},
+		{name: "Testcase #3: Positive, q-values", accept: "fr;q=0.9, pt-PT;q=0.8, en;q=0.5", want: "pt-BR"},
+		{name: "Testcase #4: Positive, order by q", accept: "en-GB;q=0.4, id-id", want: "id-ID"},
+		{name: "Testcase #5: Negative, empty header", accept: "", want: "en-US"},
+		{name: "Testcase #6: Negative, no match", accept: "ja-JP, de;q=0.5", want: "en-US"},
+		{name: "Testcase #7: Negative, rejected language", accept: "id;q=0, *;q=0.1", want: "en-US"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, catalog.Match(tt.accept))
+		})
+	}
+}
+
+func TestPluralCategoryOf(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      float64
+		want   PluralCategory
+	}{
+		{locale: "en-US", n: 1, want: PluralOne},
+		{locale: "en-US", n: 1.5, want: PluralOther},
+		{locale: "id-ID", n: 1, want: PluralOther},
+		{locale: "fr-FR", n: 0, want: PluralOne},
+		{locale: "ru", n: 21, want: PluralOne},
+		{locale: "ru", n: 12, want: PluralMany},
+		{locale: "pl", n: 24, want: PluralFew},
+		{locale: "pl", n: 25, want: PluralMany},
+		{locale: "cs", n: 3, want: PluralFew},
+		{locale: "ar", n: 2, want: PluralTwo},
+		{locale: "ar", n: 105, want: PluralFew},
+		{locale: "xx", n: 1, want: PluralOne},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, PluralCategoryOf(tt.locale, tt.n), "%s %v", tt.locale, tt.n)
+	}
+}
+
+func TestMultiErrorLocalize(t *testing.T) {
+	type register struct {
+		Email string `json:"email" validate:"required"`
+		Name  string `json:"name" validate:"min=3"`
+	}
+	err := ValidateStruct(register{Name: "ab"})
+	multiError := err.(*MultiError)
+
+	t.Run("Testcase #1: Positive, translated with rule param", func(t *testing.T) {
+		localized := multiError.Localize("id-ID")
+		assert.Equal(t, map[string]string{"email": "wajib diisi", "name": "minimal 3"}, localized.ToMap())
+		assert.Equal(t, ErrRequired, localized.Errors()[0].Unwrap().(*localizedError).Unwrap())
+	})
+	t.Run("Testcase #2: Positive, original message without translation", func(t *testing.T) {
+		assert.Equal(t, multiError.ToMap(), multiError.Localize("en-US").ToMap())
+	})
+	t.Run("Testcase #3: Negative, nil multierror", func(t *testing.T) {
+		var nilError *MultiError
+		assert.Nil(t, nilError.Localize("id-ID"))
+	})
+	t.Run("Testcase #4: Positive, response errors translated on write", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Accept-Language", "id")
+		rec := httptest.NewRecorder()
+		assert.NoError(t, WriteHTTPResponse(rec, req, NewHTTPResponseV2(http.StatusBadRequest, "error.validation", multiError)))
+		assert.JSONEq(t, `{"success":false,"code":400,"message":"validasi gagal","data":{},
+			"errors":{"email":"wajib diisi","name":"minimal 3"}}`, rec.Body.String())
+	})
+}
+
+func TestResponseV2Localize(t *testing.T) {
+	t.Run("Testcase #1: Positive, built-in message constant translated", func(t *testing.T) {
+		resp := NewHTTPResponseV2(http.StatusNotFound, ErrorDataNotFound).(*ResponseV2)
+		resp.Localize("en-US")
+		assert.Equal(t, "data not found", resp.Message)
+	})
+	t.Run("Testcase #2: Positive, message key of error mapping translated", func(t *testing.T) {
+		resp := NewHTTPErrorResponseWithContext(WithLocale(context.Background(), "id-ID"), ErrConflict).(*ResponseV2)
+		resp.Localize("en-US")
+		assert.Equal(t, "data conflict", resp.Message)
+	})
+	t.Run("Testcase #3: Negative, free text kept as is", func(t *testing.T) {
+		resp := NewHTTPResponseV2(http.StatusOK, "error").(*ResponseV2)
+		resp.Localize("id-ID")
+		assert.Equal(t, "error", resp.Message)
+	})
+	t.Run("Testcase #4: Positive, write doesn't change response", func(t *testing.T) {
+		resp := NewHTTPResponseV2(http.StatusNotFound, ErrorDataNotFound).(*ResponseV2)
+		for _, tt := range []struct{ locale, want string }{{"en", "data not found"}, {"id", ErrorDataNotFound}} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.locale)
+			rec := httptest.NewRecorder()
+			assert.NoError(t, resp.Write(rec, req))
+			assert.JSONEq(t, `{"success":false,"code":404,"message":"`+tt.want+`","data":{}}`, rec.Body.String())
+		}
+		assert.Equal(t, ErrorDataNotFound, resp.Message)
+		assert.Nil(t, resp.Data)
+	})
+}
+
+func TestLocaleMiddleware(t *testing.T) {
+	handler := LocaleMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "id-ID", LocaleFromContext(req.Context()))
+		WriteHTTPResponse(w, req, NewHTTPResponseV2(http.StatusConflict, "error.conflict"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "id,en;q=0.8")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "id-ID", rec.Header().Get("Content-Language"))
+	assert.Equal(t, `{"success":false,"code":409,"message":"data konflik dengan data yang sudah ada","data":{}}`+"\n", rec.Body.String())
+}
@@ -0,0 +1,424 @@
+package golib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonapi"
+)
+
+var (
+	// JSONAPIPageNumberParam query parameter of page number in pagination links of JSON:API document
+	JSONAPIPageNumberParam = "page[number]"
+	// JSONAPIPageSizeParam query parameter of page size in pagination links of JSON:API document
+	JSONAPIPageSizeParam = "page[size]"
+	// JSONAPIErrorPointerPrefix prefix of source.pointer of MultiError item, ex: "/data/attributes/email" for key "email"
+	JSONAPIErrorPointerPrefix = "/data/attributes"
+)
+
+// JSONAPIErrorSource source of JSON:API error object
+type JSONAPIErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// JSONAPIErrorObject JSON:API error object, jsonapi.ErrorObject doesn't have source member
+type JSONAPIErrorObject struct {
+	ID     string                 `json:"id,omitempty"`
+	Status string                 `json:"status,omitempty"`
+	Code   string                 `json:"code,omitempty"`
+	Title  string                 `json:"title,omitempty"`
+	Detail string                 `json:"detail,omitempty"`
+	Source *JSONAPIErrorSource    `json:"source,omitempty"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// JSONAPIDocument JSON:API document builder with pagination links, sparse fieldsets and compound documents
+type JSONAPIDocument struct {
+	status     int
+	data       interface{}
+	meta       map[string]interface{}
+	links      jsonapi.Links
+	fields     map[string][]string
+	include    []string
+	hasInclude bool
+	errors     []*JSONAPIErrorObject
+}
+
+// NewJSONAPIDocument create document of data, data is struct, pointer or slice of struct with jsonapi tag,
+// or payload from MarshalConvertOnePayload and MarshalConvertManyPayload
+func NewJSONAPIDocument(data interface{}) *JSONAPIDocument {
+	return &JSONAPIDocument{status: http.StatusOK, data: data}
+}
+
+// NewJSONAPIErrorDocument create errors document, every item of *MultiError in error chain become error object
+// with source.pointer (see JSONAPIErrorPointerPrefix) or source.parameter for query parameter error (see ParseJSONAPIQuery),
+// other error become single error object
+func NewJSONAPIErrorDocument(status int, err error) *JSONAPIDocument {
+	doc := &JSONAPIDocument{status: status}
+	title := http.StatusText(status)
+
+	var multiError *MultiError
+	if errors.As(err, &multiError) && multiError.HasError() {
+		for _, item := range multiError.Errors() {
+			source := &JSONAPIErrorSource{Parameter: item.Parameter}
+			if item.Parameter == "" {
+				source.Pointer = JSONAPIErrorPointerPrefix + item.Path
+			}
+			doc.errors = append(doc.errors, &JSONAPIErrorObject{
+				Status: strconv.Itoa(status),
+				Code:   item.Code,
+				Title:  title,
+				Detail: item.Error(),
+				Source: source,
+			})
+		}
+		return doc
+	}
+
+	errorObject := &JSONAPIErrorObject{Status: strconv.Itoa(status), Title: title}
+	if err != nil {
+		errorObject.Detail = err.Error()
+	}
+	doc.errors = append(doc.errors, errorObject)
+	return doc
+}
+
+// WithStatus set http status of document (ex: 201 for created resource)
+func (doc *JSONAPIDocument) WithStatus(status int) *JSONAPIDocument {
+	doc.status = status
+	return doc
+}
+
+// WithMeta merge meta (ex: Meta or map) to document meta
+func (doc *JSONAPIDocument) WithMeta(meta interface{}) *JSONAPIDocument {
+	generic, err := toGenericValue(meta)
+	if err != nil {
+		return doc
+	}
+	if m, ok := generic.(map[string]interface{}); ok {
+		if doc.meta == nil {
+			doc.meta = make(map[string]interface{})
+		}
+		for k, v := range m {
+			doc.meta[k] = v
+		}
+	}
+	return doc
+}
+
+// WithLink set document link (ex: "self")
+func (doc *JSONAPIDocument) WithLink(name, link string) *JSONAPIDocument {
+	if doc.links == nil {
+		doc.links = make(jsonapi.Links)
+	}
+	doc.links[name] = link
+	return doc
+}
+
+// WithPagination set pagination meta and links self, first, prev, next and last from GetSelfLink of request,
+// page is set with JSONAPIPageNumberParam and JSONAPIPageSizeParam query parameter
+func (doc *JSONAPIDocument) WithPagination(req *http.Request, meta Meta) *JSONAPIDocument {
+	self := GetSelfLink(req)
+	doc.WithMeta(meta)
+	doc.WithLink("self", self)
+	if meta.TotalPages < 1 {
+		return doc
+	}
+
+	doc.WithLink("first", jsonAPIPageLink(self, 1, meta.Limit))
+	doc.WithLink("last", jsonAPIPageLink(self, meta.TotalPages, meta.Limit))
+	if meta.Page > 1 {
+		doc.WithLink("prev", jsonAPIPageLink(self, meta.Page-1, meta.Limit))
+	}
+	if meta.Page < meta.TotalPages {
+		doc.WithLink("next", jsonAPIPageLink(self, meta.Page+1, meta.Limit))
+	}
+	return doc
+}
+
+// WithFields set sparse fieldset of resource type, only listed attributes and relationships is encoded
+func (doc *JSONAPIDocument) WithFields(resourceType string, fields ...string) *JSONAPIDocument {
+	if doc.fields == nil {
+		doc.fields = make(map[string][]string)
+	}
+	doc.fields[resourceType] = fields
+	return doc
+}
+
+// WithInclude set relationship paths (ex: "author", "comments.author") of included resources,
+// all related resources is included if it is not set
+func (doc *JSONAPIDocument) WithInclude(paths ...string) *JSONAPIDocument {
+	doc.hasInclude = true
+	doc.include = append(doc.include, paths...)
+	return doc
+}
+
+// WithRequest set sparse fieldsets from "fields[type]" and included resources from "include" query parameter of request
+func (doc *JSONAPIDocument) WithRequest(req *http.Request) *JSONAPIDocument {
+	query := req.URL.Query()
+	for key, values := range query {
+		if strings.HasPrefix(key, "fields[") && strings.HasSuffix(key, "]") && len(values) > 0 {
+			doc.WithFields(key[len("fields["):len(key)-1], splitList(values[len(values)-1])...)
+		}
+	}
+	if values, ok := query["include"]; ok && len(values) > 0 {
+		doc.WithInclude(splitList(values[len(values)-1])...)
+	}
+	return doc
+}
+
+// Status http status of document
+func (doc *JSONAPIDocument) Status() int {
+	return doc.status
+}
+
+// MarshalJSON encode JSON:API document
+func (doc *JSONAPIDocument) MarshalJSON() ([]byte, error) {
+	if len(doc.errors) > 0 {
+		return json.Marshal(struct {
+			Errors []*JSONAPIErrorObject  `json:"errors"`
+			Meta   map[string]interface{} `json:"meta,omitempty"`
+		}{Errors: doc.errors, Meta: doc.meta})
+	}
+
+	var payload jsonapi.Payloader
+	switch data := doc.data.(type) {
+	case jsonapi.Payloader:
+		payload = data
+	case nil:
+		payload = &jsonapi.OnePayload{}
+	default:
+		p, err := jsonapi.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		payload = p
+	}
+
+	var meta *jsonapi.Meta
+	if doc.meta != nil {
+		m := jsonapi.Meta(doc.meta)
+		meta = &m
+	}
+	var links *jsonapi.Links
+	if doc.links != nil {
+		links = &doc.links
+	}
+
+	// payload can be the caller's, so it is copied instead of modified
+	switch p := payload.(type) {
+	case *jsonapi.OnePayload:
+		copied := *p
+		var primary []*jsonapi.Node
+		if p.Data != nil {
+			primary = append(primary, p.Data)
+		}
+		primary, copied.Included = doc.compound(primary, p.Included)
+		if len(primary) > 0 {
+			copied.Data = primary[0]
+		}
+		copied.Meta, copied.Links = mergeJSONAPIMeta(p.Meta, meta), mergeJSONAPILinks(p.Links, links)
+		payload = &copied
+	case *jsonapi.ManyPayload:
+		copied := *p
+		copied.Data, copied.Included = doc.compound(p.Data, p.Included)
+		copied.Meta, copied.Links = mergeJSONAPIMeta(p.Meta, meta), mergeJSONAPILinks(p.Links, links)
+		payload = &copied
+	}
+	return json.Marshal(payload)
+}
+
+// Write write document with Content-Type: application/vnd.api+json
+func (doc *JSONAPIDocument) Write(w http.ResponseWriter) error {
+	b, err := doc.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", JSONAPIContentType)
+	w.WriteHeader(doc.status)
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// compound filter included resources with include paths, sort them by type and id
+// and apply sparse fieldsets to copy of primary and included resources
+func (doc *JSONAPIDocument) compound(primary, included []*jsonapi.Node) ([]*jsonapi.Node, []*jsonapi.Node) {
+	if doc.hasInclude {
+		index := make(map[string]*jsonapi.Node, len(included))
+		for _, node := range included {
+			index[node.Type+"/"+node.ID] = node
+		}
+
+		keep := make(map[*jsonapi.Node]bool)
+		for _, path := range doc.include {
+			current := primary
+			for _, name := range strings.Split(path, ".") {
+				var next []*jsonapi.Node
+				for _, node := range current {
+					for _, linkage := range relationshipNodes(node.Relationships[name]) {
+						if related, ok := index[linkage.Type+"/"+linkage.ID]; ok {
+							keep[related] = true
+							next = append(next, related)
+						}
+					}
+				}
+				current = next
+			}
+		}
+
+		var filtered []*jsonapi.Node
+		for _, node := range included {
+			if keep[node] {
+				filtered = append(filtered, node)
+			}
+		}
+		included = filtered
+	} else {
+		included = append([]*jsonapi.Node(nil), included...)
+	}
+
+	sort.SliceStable(included, func(i, j int) bool {
+		if included[i].Type != included[j].Type {
+			return included[i].Type < included[j].Type
+		}
+		return included[i].ID < included[j].ID
+	})
+
+	return doc.sparse(primary), doc.sparse(included)
+}
+
+// sparse copy nodes with attributes and relationships filtered by sparse fieldset of their type
+func (doc *JSONAPIDocument) sparse(nodes []*jsonapi.Node) []*jsonapi.Node {
+	if len(doc.fields) == 0 || nodes == nil {
+		return nodes
+	}
+
+	result := make([]*jsonapi.Node, len(nodes))
+	for i, node := range nodes {
+		result[i] = node
+		if fields, ok := doc.fields[node.Type]; ok {
+			copied := *node
+			copied.Attributes = filterJSONAPIMembers(node.Attributes, fields)
+			copied.Relationships = filterJSONAPIMembers(node.Relationships, fields)
+			result[i] = &copied
+		}
+	}
+	return result
+}
+
+// JSONAPIErrors convert errors map of ResponseV2 to JSON:API error objects sorted by key
+func JSONAPIErrors(status int, title string, errs map[string]string) []*JSONAPIErrorObject {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errorObjects := make([]*JSONAPIErrorObject, 0, len(keys))
+	for _, key := range keys {
+		errorObjects = append(errorObjects, &JSONAPIErrorObject{
+			Status: strconv.Itoa(status),
+			Title:  title,
+			Detail: errs[key],
+			Source: &JSONAPIErrorSource{Pointer: JSONAPIErrorPointerPrefix + KeyToJSONPointer(key)},
+		})
+	}
+	return errorObjects
+}
+
+func relationshipNodes(relationship interface{}) []*jsonapi.Node {
+	switch rel := relationship.(type) {
+	case *jsonapi.RelationshipOneNode:
+		if rel.Data != nil {
+			return []*jsonapi.Node{rel.Data}
+		}
+	case *jsonapi.RelationshipManyNode:
+		return rel.Data
+	}
+	return nil
+}
+
+func filterJSONAPIMembers(members map[string]interface{}, fields []string) map[string]interface{} {
+	if members == nil {
+		return nil
+	}
+
+	filtered := make(map[string]interface{})
+	for _, field := range fields {
+		if v, ok := members[field]; ok {
+			filtered[field] = v
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+func mergeJSONAPIMeta(dst, src *jsonapi.Meta) *jsonapi.Meta {
+	if dst == nil {
+		return src
+	}
+	if src == nil {
+		return dst
+	}
+
+	merged := make(jsonapi.Meta, len(*dst)+len(*src))
+	for k, v := range *dst {
+		merged[k] = v
+	}
+	for k, v := range *src {
+		merged[k] = v
+	}
+	return &merged
+}
+
+func mergeJSONAPILinks(dst, src *jsonapi.Links) *jsonapi.Links {
+	if dst == nil {
+		return src
+	}
+	if src == nil {
+		return dst
+	}
+
+	merged := make(jsonapi.Links, len(*dst)+len(*src))
+	for k, v := range *dst {
+		merged[k] = v
+	}
+	for k, v := range *src {
+		merged[k] = v
+	}
+	return &merged
+}
+
+func jsonAPIPageLink(self string, page, size int) string {
+	u, err := url.Parse(self)
+	if err != nil {
+		return self
+	}
+
+	query := u.Query()
+	query.Set(JSONAPIPageNumberParam, strconv.Itoa(page))
+	if size > 0 {
+		query.Set(JSONAPIPageSizeParam, strconv.Itoa(size))
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
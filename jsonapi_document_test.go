@@ -0,0 +1,148 @@
+package golib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonAPIPerson struct {
+	ID   string `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type jsonAPIComment struct {
+	ID     string         `jsonapi:"primary,comments"`
+	Body   string         `jsonapi:"attr,body"`
+	Author *jsonAPIPerson `jsonapi:"relation,author"`
+}
+
+type jsonAPIArticle struct {
+	ID       string            `jsonapi:"primary,articles"`
+	Title    string            `jsonapi:"attr,title"`
+	Body     string            `jsonapi:"attr,body"`
+	Author   *jsonAPIPerson    `jsonapi:"relation,author"`
+	Comments []*jsonAPIComment `jsonapi:"relation,comments"`
+}
+
+func newJSONAPIArticles() []*jsonAPIArticle {
+	dan := &jsonAPIPerson{ID: "9", Name: "Dan"}
+	ani := &jsonAPIPerson{ID: "10", Name: "Ani"}
+	return []*jsonAPIArticle{{
+		ID: "1", Title: "JSON:API", Body: "paints my bikeshed", Author: dan,
+		Comments: []*jsonAPIComment{{ID: "5", Body: "first", Author: ani}},
+	}}
+}
+
+func TestJSONAPIDocument(t *testing.T) {
+	t.Run("Testcase #1: Positive, pagination links and meta", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/orders?page[number]=2&page[size]=1&sort=-id", nil)
+		rec := httptest.NewRecorder()
+
+		doc := NewJSONAPIDocument([]*jsonAPIModel{{ID: "1", Name: "order"}}).
+			WithPagination(req, Meta{Page: 2, Limit: 1, TotalRecords: 3, TotalPages: 3})
+		assert.NoError(t, doc.Write(rec))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, JSONAPIContentType, rec.Header().Get("Content-Type"))
+
+		link := func(page int) string {
+			return fmt.Sprintf("http://example.com/orders?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=1&sort=-id", page)
+		}
+		assert.JSONEq(t, `{
+			"data": [{"type": "orders", "id": "1", "attributes": {"name": "order"}}],
+			"meta": {"page": 2, "limit": 1, "totalRecords": 3, "totalPages": 3},
+			"links": {
+				"self": "http://example.com/orders?page[number]=2&page[size]=1&sort=-id",
+				"first": "`+link(1)+`", "prev": "`+link(1)+`", "next": "`+link(3)+`", "last": "`+link(3)+`"
+			}
+		}`, rec.Body.String())
+	})
+	t.Run("Testcase #2: Positive, include and sparse fieldsets from request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/articles?include=comments&fields[articles]=title,comments&fields[comments]=body", nil)
+		b, err := NewJSONAPIDocument(newJSONAPIArticles()).WithRequest(req).MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"data": [{
+				"type": "articles", "id": "1", "attributes": {"title": "JSON:API"},
+				"relationships": {"comments": {"data": [{"type": "comments", "id": "5"}]}}
+			}],
+			"included": [{"type": "comments", "id": "5", "attributes": {"body": "first"}}]
+		}`, string(b))
+	})
+	t.Run("Testcase #3: Positive, nested include path", func(t *testing.T) {
+		b, err := NewJSONAPIDocument(newJSONAPIArticles()).WithInclude("comments.author").WithFields("articles", "title").MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"data": [{"type": "articles", "id": "1", "attributes": {"title": "JSON:API"}}],
+			"included": [
+				{"type": "comments", "id": "5", "attributes": {"body": "first"},
+				 "relationships": {"author": {"data": {"type": "people", "id": "10"}}}},
+				{"type": "people", "id": "10", "attributes": {"name": "Ani"}}
+			]
+		}`, string(b))
+	})
+	t.Run("Testcase #4: Positive, empty include and payload from MarshalConvertOnePayload", func(t *testing.T) {
+		payload, err := MarshalConvertOnePayload(newJSONAPIArticles()[0])
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/articles/1?include=", nil)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, NewJSONAPIDocument(payload).WithRequest(req).WithStatus(http.StatusCreated).WithMeta(map[string]string{"version": "1"}).Write(rec))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NotContains(t, rec.Body.String(), `"included"`)
+		assert.Contains(t, rec.Body.String(), `"meta":{"version":"1"}`)
+	})
+	t.Run("Testcase #5: Positive, payload rendered twice with different sparse fieldsets", func(t *testing.T) {
+		payload, err := MarshalConvertManyPayload(newJSONAPIArticles())
+		assert.NoError(t, err)
+
+		b, err := NewJSONAPIDocument(payload).WithFields("articles", "title").WithInclude().MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"data": [{"type": "articles", "id": "1", "attributes": {"title": "JSON:API"}}]}`, string(b))
+
+		b, err = NewJSONAPIDocument(payload).WithFields("articles", "body").WithMeta(map[string]int{"total": 1}).MarshalJSON()
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), `"attributes":{"body":"paints my bikeshed"}`)
+		assert.Contains(t, string(b), `"included":[`)
+		assert.Equal(t, map[string]interface{}{"title": "JSON:API", "body": "paints my bikeshed"}, payload.Data[0].Attributes)
+		assert.Len(t, payload.Included, 3)
+		assert.Nil(t, payload.Meta)
+	})
+	t.Run("Testcase #6: Negative, data without jsonapi tag", func(t *testing.T) {
+		_, err := NewJSONAPIDocument(make(chan int)).MarshalJSON()
+		assert.Error(t, err)
+	})
+}
+
+func TestNewJSONAPIErrorDocument(t *testing.T) {
+	multiError := NewMultiError()
+	multiError.AppendCode("email", ErrorCodeRequired, ErrRequired)
+	multiError.AppendCode("address.city", "max", errors.New("must be at most 10 characters"))
+
+	t.Run("Testcase #1: Positive, multierror with source pointer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		assert.NoError(t, NewJSONAPIErrorDocument(http.StatusUnprocessableEntity, fmt.Errorf("bind: %w", multiError)).Write(rec))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, JSONAPIContentType, rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"errors": [
+			{"status": "422", "code": "required", "title": "Unprocessable Entity", "detail": "is required", "source": {"pointer": "/data/attributes/email"}},
+			{"status": "422", "code": "max", "title": "Unprocessable Entity", "detail": "must be at most 10 characters", "source": {"pointer": "/data/attributes/address/city"}}
+		]}`, rec.Body.String())
+	})
+	t.Run("Testcase #2: Positive, query error with source parameter", func(t *testing.T) {
+		_, err := ParseJSONAPIQuery(url.Values{"filter[total][gt]": {"abc"}}, jsonAPIOrder{})
+		b, _ := NewJSONAPIErrorDocument(http.StatusBadRequest, err).MarshalJSON()
+		assert.JSONEq(t, `{"errors": [{"status": "400", "code": "invalid_format", "title": "Bad Request",
+			"detail": "Cannot parse 'abc' to type float", "source": {"parameter": "filter[total][gt]"}}]}`, string(b))
+	})
+	t.Run("Testcase #3: Negative, single error", func(t *testing.T) {
+		b, err := NewJSONAPIErrorDocument(http.StatusNotFound, ErrNotFound).MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"errors": [{"status": "404", "title": "Not Found", "detail": "data tidak ditemukan"}]}`, string(b))
+	})
+}
@@ -0,0 +1,378 @@
+package golib
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+// JSON:API filter operators
+const (
+	FilterEqual          = "eq"
+	FilterNotEqual       = "ne"
+	FilterGreater        = "gt"
+	FilterGreaterOrEqual = "gte"
+	FilterLess           = "lt"
+	FilterLessOrEqual    = "lte"
+	FilterLike           = "like"
+	FilterIn             = "in"
+)
+
+// jsonAPILikeEscape escape character of LIKE filter, backslash isn't used because it must be escaped in MySQL string literal
+const jsonAPILikeEscape = "!"
+
+var (
+	// JSONAPIDefaultPageSize page size when page[size] is not set
+	JSONAPIDefaultPageSize = 10
+	// JSONAPIMaxPageSize maximum page size, bigger page[size] is clamped to this value
+	JSONAPIMaxPageSize = 100
+
+	jsonAPIFilterConditions = map[string]string{
+		FilterEqual:          "%s = ?",
+		FilterNotEqual:       "%s <> ?",
+		FilterGreater:        "%s > ?",
+		FilterGreaterOrEqual: "%s >= ?",
+		FilterLess:           "%s < ?",
+		FilterLessOrEqual:    "%s <= ?",
+		FilterLike:           "%s LIKE ? ESCAPE '" + jsonAPILikeEscape + "'",
+		FilterIn:             "%s IN (?)",
+	}
+
+	// jsonAPILikeReplacer escape wildcard and escape character in value of LIKE filter
+	jsonAPILikeReplacer = strings.NewReplacer(jsonAPILikeEscape, jsonAPILikeEscape+jsonAPILikeEscape,
+		"%", jsonAPILikeEscape+"%", "_", jsonAPILikeEscape+"_")
+)
+
+// JSONAPIQuery JSON:API query parameters (filter, sort, page, include and fields) parsed by ParseJSONAPIQuery
+type JSONAPIQuery struct {
+	Filters []JSONAPIFilter
+	Sort    []JSONAPISort
+	Page    JSONAPIPage
+	Include []string
+	Fields  map[string][]string
+
+	// preloads gorm preload path of include (ex: "Comments.Author" for "comments.author")
+	preloads []string
+}
+
+// JSONAPIFilter filter of field from filter[field][operator]=value, Values is converted to type of field
+type JSONAPIFilter struct {
+	Field    string
+	Column   string
+	Operator string
+	Values   []interface{}
+}
+
+// JSONAPISort sort field from sort=-field
+type JSONAPISort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// JSONAPIPage page from page[number], page[size] and page[cursor]
+type JSONAPIPage struct {
+	Number int
+	Size   int
+	Cursor string
+}
+
+// jsonAPIQueryField cached whitelist of struct field for ParseJSONAPIQuery
+type jsonAPIQueryField struct {
+	name       string
+	goName     string
+	column     string
+	typ        reflect.Type
+	tag        reflect.StructTag
+	operators  []string
+	sortable   bool
+	includable bool
+	relation   reflect.Type
+}
+
+type jsonAPIQueryPlan struct {
+	resourceType string
+	fields       map[string]*jsonAPIQueryField
+}
+
+// jsonAPIQueryPlans cached jsonAPIQueryPlan by struct type
+var jsonAPIQueryPlans sync.Map
+
+// ParseJSONAPIQuery parse JSON:API query parameters with whitelist declared in tags of target struct (model with jsonapi tag):
+// `filter:"eq,in"` allowed filter operators, `sort:"true"` sortable field and `include:"true"` includable relation.
+// Column of field is taken from gorm column tag or snake case of field name. Every violation is returned in *MultiError
+// with query parameter as key and parameter of error item (ex: "filter[price][gt]")
+func ParseJSONAPIQuery(query url.Values, target interface{}) (*JSONAPIQuery, error) {
+	typ := reflect.TypeOf(target)
+	if typ == nil || indirectType(typ).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid target type %v: must struct", typ)
+	}
+	typ = indirectType(typ)
+	plan := getJSONAPIQueryPlan(typ)
+
+	q := &JSONAPIQuery{Page: JSONAPIPage{Number: 1, Size: JSONAPIDefaultPageSize}}
+	errs := NewMultiError()
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := query[key]
+		if len(values) == 0 {
+			continue
+		}
+		value := values[len(values)-1]
+
+		switch {
+		case key == "sort":
+			q.parseSort(plan, value, errs)
+		case key == "include":
+			q.parseInclude(plan, value, errs)
+		case key == "page[number]" || key == "page[size]":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				appendQueryError(errs, key, ErrorCodeInvalidFormat, fmt.Errorf("Cannot parse '%s' to type positive number", value))
+				continue
+			}
+			if key == "page[number]" {
+				q.Page.Number = n
+				continue
+			}
+			if n > JSONAPIMaxPageSize {
+				n = JSONAPIMaxPageSize
+			}
+			q.Page.Size = n
+		case key == "page[cursor]":
+			q.Page.Cursor = value
+		case strings.HasPrefix(key, "fields[") && strings.HasSuffix(key, "]"):
+			q.parseFields(plan, key, value, errs)
+		case strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]"):
+			q.parseFilter(plan, key, values, errs)
+		}
+	}
+
+	if errs.HasError() {
+		return q, errs
+	}
+	return q, nil
+}
+
+// appendQueryError append error of query parameter, parameter is kept so JSON:API error has source.parameter
+func appendQueryError(errs *MultiError, parameter, code string, err error) {
+	errs.AppendItem(&ErrorItem{Key: parameter, Code: code, Parameter: parameter, Err: err})
+}
+
+func (q *JSONAPIQuery) parseSort(plan *jsonAPIQueryPlan, value string, errs *MultiError) {
+	for _, name := range splitList(value) {
+		s := JSONAPISort{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+		field, ok := plan.fields[s.Field]
+		if !ok || !field.sortable {
+			appendQueryError(errs, "sort", ErrorCodeNotAllowed, fmt.Errorf("cannot sort by '%s'", s.Field))
+			continue
+		}
+		s.Column = field.column
+		q.Sort = append(q.Sort, s)
+	}
+}
+
+func (q *JSONAPIQuery) parseInclude(plan *jsonAPIQueryPlan, value string, errs *MultiError) {
+	for _, path := range splitList(value) {
+		current := plan
+		var preload []string
+		for _, name := range strings.Split(path, ".") {
+			field, ok := current.fields[name]
+			if !ok || !field.includable {
+				appendQueryError(errs, "include", ErrorCodeNotAllowed, fmt.Errorf("cannot include '%s'", path))
+				preload = nil
+				break
+			}
+			preload = append(preload, field.goName)
+			current = getJSONAPIQueryPlan(field.relation)
+		}
+
+		if preload != nil {
+			q.Include = append(q.Include, path)
+			q.preloads = append(q.preloads, strings.Join(preload, "."))
+		}
+	}
+}
+
+func (q *JSONAPIQuery) parseFields(plan *jsonAPIQueryPlan, key, value string, errs *MultiError) {
+	resourceType := key[len("fields[") : len(key)-1]
+	typePlan := findJSONAPIQueryPlan(plan, resourceType, make(map[*jsonAPIQueryPlan]bool))
+	if typePlan == nil {
+		appendQueryError(errs, key, ErrorCodeNotAllowed, fmt.Errorf("unknown resource type '%s'", resourceType))
+		return
+	}
+
+	fields := splitList(value)
+	for _, name := range fields {
+		if _, ok := typePlan.fields[name]; !ok {
+			appendQueryError(errs, key, ErrorCodeNotAllowed, fmt.Errorf("unknown field '%s'", name))
+			return
+		}
+	}
+	if q.Fields == nil {
+		q.Fields = make(map[string][]string)
+	}
+	q.Fields[resourceType] = fields
+}
+
+func (q *JSONAPIQuery) parseFilter(plan *jsonAPIQueryPlan, key string, values []string, errs *MultiError) {
+	parts := strings.Split(key[len("filter["):len(key)-1], "][")
+	filter := JSONAPIFilter{Field: parts[0], Operator: FilterEqual}
+	if len(parts) == 2 {
+		filter.Operator = parts[1]
+	}
+
+	field, ok := plan.fields[filter.Field]
+	if !ok || len(parts) > 2 || !StringInSlice(filter.Operator, field.operators) {
+		appendQueryError(errs, key, ErrorCodeNotAllowed, fmt.Errorf("cannot filter by '%s' with operator '%s'", filter.Field, filter.Operator))
+		return
+	}
+	filter.Column = field.column
+
+	rawValues := values[len(values)-1:]
+	if filter.Operator == FilterIn {
+		rawValues = splitList(rawValues[0])
+	}
+	for _, raw := range rawValues {
+		value := reflect.New(field.typ).Elem()
+		if err := parseStringToValue(value, raw, field.tag); err != nil {
+			appendQueryError(errs, key, ErrorCodeInvalidFormat, err)
+			return
+		}
+		if filter.Operator == FilterLike {
+			filter.Values = append(filter.Values, "%"+jsonAPILikeReplacer.Replace(raw)+"%")
+			continue
+		}
+		filter.Values = append(filter.Values, value.Interface())
+	}
+	if len(filter.Values) == 0 {
+		appendQueryError(errs, key, ErrorCodeInvalidFormat, fmt.Errorf("filter value of '%s' is empty", filter.Field))
+		return
+	}
+	q.Filters = append(q.Filters, filter)
+}
+
+// Offset number of record skipped before current page
+func (q *JSONAPIQuery) Offset() int {
+	return (q.Page.Number - 1) * q.Page.Size
+}
+
+// Meta pagination meta of current page from total records (see JSONAPIDocument.WithPagination)
+func (q *JSONAPIQuery) Meta(totalRecords int) Meta {
+	totalPages := 0
+	if q.Page.Size > 0 {
+		totalPages = (totalRecords + q.Page.Size - 1) / q.Page.Size
+	}
+	return Meta{Page: q.Page.Number, Limit: q.Page.Size, TotalRecords: totalRecords, TotalPages: totalPages}
+}
+
+// Scope gorm scope which apply filters, sort, page (offset is not used with cursor) and preload of included relations,
+// ex: GetReadDB().Scopes(q.Scope).Find(&orders)
+func (q *JSONAPIQuery) Scope(db *gorm.DB) *gorm.DB {
+	for _, filter := range q.Filters {
+		condition := fmt.Sprintf(jsonAPIFilterConditions[filter.Operator], filter.Column)
+		if filter.Operator == FilterIn {
+			db = db.Where(condition, filter.Values)
+		} else {
+			db = db.Where(condition, filter.Values[0])
+		}
+	}
+	for _, s := range q.Sort {
+		direction := "ASC"
+		if s.Desc {
+			direction = "DESC"
+		}
+		db = db.Order(s.Column + " " + direction)
+	}
+	if q.Page.Cursor == "" {
+		db = db.Offset(q.Offset())
+	}
+	db = db.Limit(q.Page.Size)
+	for _, preload := range q.preloads {
+		db = db.Preload(preload)
+	}
+	return db
+}
+
+func getJSONAPIQueryPlan(typ reflect.Type) *jsonAPIQueryPlan {
+	if plan, ok := jsonAPIQueryPlans.Load(typ); ok {
+		return plan.(*jsonAPIQueryPlan)
+	}
+
+	plan := &jsonAPIQueryPlan{fields: make(map[string]*jsonAPIQueryField)}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		args := strings.Split(field.Tag.Get("jsonapi"), ",")
+		if field.PkgPath != "" || len(args) < 2 {
+			continue
+		}
+
+		f := &jsonAPIQueryField{name: args[1], goName: field.Name, typ: field.Type, tag: field.Tag}
+		switch args[0] {
+		case "primary":
+			plan.resourceType = args[1]
+			f.name = "id"
+		case "relation":
+			f.relation = indirectType(field.Type)
+			if f.relation.Kind() == reflect.Slice {
+				f.relation = indirectType(f.relation.Elem())
+			}
+			if f.relation.Kind() == reflect.Struct {
+				f.includable, _ = strconv.ParseBool(field.Tag.Get("include"))
+			}
+			plan.fields[f.name] = f
+			continue
+		case "attr":
+		default:
+			continue
+		}
+
+		f.typ = indirectType(field.Type)
+		f.column = gorm.ToColumnName(field.Name)
+		for _, setting := range strings.Split(field.Tag.Get("gorm"), ";") {
+			if kv := strings.SplitN(setting, ":", 2); len(kv) == 2 && strings.ToLower(strings.TrimSpace(kv[0])) == "column" {
+				f.column = strings.TrimSpace(kv[1])
+			}
+		}
+		f.sortable, _ = strconv.ParseBool(field.Tag.Get("sort"))
+		f.operators = splitList(field.Tag.Get("filter"))
+		plan.fields[f.name] = f
+	}
+
+	actual, _ := jsonAPIQueryPlans.LoadOrStore(typ, plan)
+	return actual.(*jsonAPIQueryPlan)
+}
+
+// findJSONAPIQueryPlan find plan of resource type in plan and its relations
+func findJSONAPIQueryPlan(plan *jsonAPIQueryPlan, resourceType string, visited map[*jsonAPIQueryPlan]bool) *jsonAPIQueryPlan {
+	if plan.resourceType == resourceType {
+		return plan
+	}
+	visited[plan] = true
+
+	for _, field := range plan.fields {
+		if field.relation == nil || field.relation.Kind() != reflect.Struct {
+			continue
+		}
+		relationPlan := getJSONAPIQueryPlan(field.relation)
+		if visited[relationPlan] {
+			continue
+		}
+		if found := findJSONAPIQueryPlan(relationPlan, resourceType, visited); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package golib
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonAPICustomer struct {
+	ID   string `jsonapi:"primary,customers" filter:"eq"`
+	Name string `jsonapi:"attr,name" sort:"true"`
+}
+
+type jsonAPIOrderItem struct {
+	ID  string `jsonapi:"primary,items"`
+	SKU string `jsonapi:"attr,sku"`
+}
+
+type jsonAPIOrder struct {
+	ID        string              `jsonapi:"primary,orders" filter:"eq,in"`
+	Status    string              `jsonapi:"attr,status" filter:"eq,ne,in" sort:"true"`
+	Total     float64             `jsonapi:"attr,total" filter:"gt,gte,lt,lte" sort:"true" gorm:"column:grand_total"`
+	Note      string              `jsonapi:"attr,note" filter:"like"`
+	CreatedAt time.Time           `jsonapi:"attr,createdAt,iso8601" filter:"gte,lte" sort:"true" format:"2006-01-02"`
+	Customer  *jsonAPICustomer    `jsonapi:"relation,customer" include:"true"`
+	Items     []*jsonAPIOrderItem `jsonapi:"relation,items"`
+}
+
+func TestParseJSONAPIQuery(t *testing.T) {
+	t.Run("Testcase #1: Positive, all parameters", func(t *testing.T) {
+		query, _ := url.ParseQuery("filter[status][in]=paid,shipped&filter[total][gte]=100.5&filter[createdAt][gte]=2020-03-01" +
+			"&sort=-createdAt,total&page[number]=3&page[size]=500&include=customer&fields[orders]=status,total&fields[customers]=name")
+
+		q, err := ParseJSONAPIQuery(query, (*jsonAPIOrder)(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, []JSONAPIFilter{
+			{Field: "createdAt", Column: "created_at", Operator: FilterGreaterOrEqual, Values: []interface{}{time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}},
+			{Field: "status", Column: "status", Operator: FilterIn, Values: []interface{}{"paid", "shipped"}},
+			{Field: "total", Column: "grand_total", Operator: FilterGreaterOrEqual, Values: []interface{}{100.5}},
+		}, q.Filters)
+		assert.Equal(t, []JSONAPISort{{Field: "createdAt", Column: "created_at", Desc: true}, {Field: "total", Column: "grand_total"}}, q.Sort)
+		assert.Equal(t, JSONAPIPage{Number: 3, Size: JSONAPIMaxPageSize}, q.Page)
+		assert.Equal(t, 200, q.Offset())
+		assert.Equal(t, []string{"customer"}, q.Include)
+		assert.Equal(t, map[string][]string{"orders": {"status", "total"}, "customers": {"name"}}, q.Fields)
+		assert.Equal(t, Meta{Page: 3, Limit: 100, TotalRecords: 250, TotalPages: 3}, q.Meta(250))
+	})
+	t.Run("Testcase #2: Positive, default", func(t *testing.T) {
+		q, err := ParseJSONAPIQuery(url.Values{"filter[status]": {"paid"}, "page[cursor]": {"abc"}}, jsonAPIOrder{})
+		assert.NoError(t, err)
+		assert.Equal(t, []JSONAPIFilter{{Field: "status", Column: "status", Operator: FilterEqual, Values: []interface{}{"paid"}}}, q.Filters)
+		assert.Equal(t, JSONAPIPage{Number: 1, Size: JSONAPIDefaultPageSize, Cursor: "abc"}, q.Page)
+	})
+	t.Run("Testcase #3: Negative, whitelist violations", func(t *testing.T) {
+		query, _ := url.ParseQuery("filter[note][eq]=x&filter[secret]=1&filter[total][gt]=abc&filter[id][in]=" +
+			"&sort=note&include=items,customer.orders&fields[orders]=password&fields[users]=name&page[number]=0")
+
+		_, err := ParseJSONAPIQuery(query, &jsonAPIOrder{})
+		multiError, ok := err.(*MultiError)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]string{
+			"fields[orders]":    "unknown field 'password'",
+			"fields[users]":     "unknown resource type 'users'",
+			"filter[id][in]":    "filter value of 'id' is empty",
+			"filter[note][eq]":  "cannot filter by 'note' with operator 'eq'",
+			"filter[secret]":    "cannot filter by 'secret' with operator 'eq'",
+			"filter[total][gt]": "Cannot parse 'abc' to type float",
+			"include":           "cannot include 'items'; cannot include 'customer.orders'",
+			"page[number]":      "Cannot parse '0' to type positive number",
+			"sort":              "cannot sort by 'note'",
+		}, multiError.ToMap())
+		assert.Equal(t, ErrorCodeNotAllowed, multiError.Errors()[0].Code)
+		assert.Equal(t, "fields[orders]", multiError.Errors()[0].Parameter)
+		assert.Empty(t, multiError.Errors()[0].Path)
+	})
+	t.Run("Testcase #4: Negative, invalid target", func(t *testing.T) {
+		_, err := ParseJSONAPIQuery(url.Values{}, "orders")
+		assert.Error(t, err)
+	})
+}
+
+func TestJSONAPIQueryScope(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	gormDB, _ := gorm.Open("postgres", db)
+
+	query, _ := url.ParseQuery("filter[status][in]=paid,shipped&filter[note][like]=50%25_off!&sort=-total&page[number]=2&page[size]=5")
+	q, err := ParseJSONAPIQuery(query, jsonAPIOrder{})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE (note LIKE $1 ESCAPE '!') AND (status IN ($2,$3)) ORDER BY grand_total DESC LIMIT 5 OFFSET 5`)).
+		WithArgs("%50!%!_off!!%", "paid", "shipped").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow("1", "paid"))
+
+	var orders []struct {
+		ID     string
+		Status string
+	}
+	assert.NoError(t, gormDB.Table("orders").Scopes(q.Scope).Find(&orders).Error)
+	assert.Len(t, orders, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -136,7 +136,7 @@ func validate(schema *gojsonschema.Schema, document gojsonschema.JSONLoader) *go
 			}
 
 			field := strings.Replace(desc.Field(), "(root)", "property", 1)
-			multiError.Append(field, fmt.Errorf("%v", errMsg))
+			multiError.AppendItem(&golib.ErrorItem{Key: field, Code: desc.Type(), Err: fmt.Errorf("%v", errMsg), Params: desc.Details()})
 		}
 	}
 
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"encoding/json"
@@ -69,13 +72,25 @@ var (
 	Env string
 
 	storageDir = os.Getenv("STORAGE_DIR")
+
+	// requestLogFiles current rotating file of RequestResponse by directory and code
+	requestLogFilesMu sync.Mutex
+	requestLogFiles   = make(map[string]*RotatingFile)
 )
 
-// InitLogger function init logger
+// InitLogger set topic, tag and environment of log entry and rebuild output of standard logrus logger
+// with DefaultLoggerOptions
 func InitLogger(topic, tag, env string) {
+	InitLoggerWithOptions(topic, tag, env, DefaultLoggerOptions)
+}
+
+// InitLoggerWithOptions set topic, tag and environment of log entry and rebuild output of standard logrus logger
+// with opts. Output which can't be opened is skipped and its error is returned
+func InitLoggerWithOptions(topic, tag, env string, opts LoggerOptions) error {
 	TOPIC = topic
 	LogTag = tag
 	Env = env
+	return configureLogger(log.StandardLogger(), opts)
 }
 
 // LogContext function for logging the context of echo
@@ -101,56 +116,20 @@ func LogContext(c string, s string, customeTags []map[string]interface{}) *log.E
 	return log.WithFields(result)
 }
 
-// Log function for returning entry type
+// Log queue log entry to DefaultLogger
 // level log.Level
 // message string message of log
 // context string context of log
 // scope string scope of log
 func Log(level Level, message string, context string, scope string, customeTags ...map[string]interface{}) {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Println(r)
-			}
-		}()
-
-		entry := LogContext(context, scope, customeTags)
-		switch level {
-		case DebugLevel:
-			entry.Debug(message)
-		case InfoLevel:
-			entry.Info(message)
-		case WarnLevel:
-			entry.Warn(message)
-		case ErrorLevel:
-			entry.Error(message)
-		case FatalLevel:
-			entry.Fatal(message)
-		case PanicLevel:
-			entry.Panic(message)
-		}
-	}()
+	fields := append([]map[string]interface{}{{"context": context, "scope": scope}}, customeTags...)
+	DefaultLogger.Log(nil, level, message, fields...)
 }
 
-// LogError logging error
+// LogError queue error log entry with JSON of messageData as message to DefaultLogger
 func LogError(err error, context string, messageData interface{}) {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Println(r)
-			}
-		}()
-
-		entry := log.WithFields(log.Fields{
-			"topic":      TOPIC,
-			"context":    context,
-			"error":      err,
-			"server_env": Env,
-		})
-
-		jsonStr, _ := json.Marshal(messageData)
-		entry.Error(string(jsonStr))
-	}()
+	jsonStr, _ := json.Marshal(messageData)
+	DefaultLogger.Log(nil, ErrorLevel, string(jsonStr), map[string]interface{}{"context": context, "error": err})
 }
 
 // ResultLogger result logger interface
@@ -184,6 +163,13 @@ func (flo *FileResultLogger) LastError() error {
 	return flo.lastError
 }
 
+// copyResultLogger copy of FileResultLogger without last error
+func (flo *FileResultLogger) copyResultLogger() ResultLogger {
+	c := *flo
+	c.lastError = nil
+	return &c
+}
+
 // createOrIgnore function for creating or ignoring error
 // p string
 func (flo *FileResultLogger) createOrIgnore(p string) error {
@@ -213,11 +199,13 @@ func (flo *FileResultLogger) GetFileName(c string) string {
 	return flo.baseDir + "/" + c + "/" + f
 }
 
-// Get  function to get log
-// p string file name
+// Get function to get data stored by Store
+// p string file name returned by Store or its path relative to base directory
 func (flo *FileResultLogger) Get(p string) string {
-	base := storageDir
-	f := base + "/archive/" + p
+	f := p
+	if flo.baseDir != "" && !strings.HasPrefix(p, flo.baseDir) {
+		f = flo.baseDir + "/" + p
+	}
 	data, err := ioutil.ReadFile(f)
 	if err != nil {
 		flo.lastError = err
@@ -246,48 +234,60 @@ func (flo *FileResultLogger) Store(c string, d []byte) string {
 	return fileName
 }
 
-// RequestResponse function for storing request and response into file
-// c string content
+// RequestResponse function for appending request and response into rotating file <STORAGE_DIR>/logs/YYYYMMDD.<code>
+// c string code
 // dt string date time
 func (flo *FileResultLogger) RequestResponse(c string, dt string) string {
 	t := time.Now()
 
 	// set the value of data
 	dir := storageDir + "/logs/"
-	filename := fmt.Sprintf("%s%s.%s", dir, t.Format("20060102"), c)
 	val := fmt.Sprintf("%s : %s", t.Format("15:04:05"), dt)
 
-	if err := flo.createOrIgnore(dir); err == nil {
-		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0775)
-		if err == nil {
-			if _, err = f.WriteString(val); err != nil {
-				panic(err)
-			}
-		}
+	if err := flo.createOrIgnore(dir); err != nil {
+		return ""
+	}
 
-		defer f.Close()
+	if err := writeRequestLog(dir, c, t, []byte(val)); err != nil {
+		flo.lastError = err
 	}
 
 	return ""
 }
 
-// GetResultLogger function for getting log result
-func GetResultLogger() ResultLogger {
-	base := os.Getenv("LOG_DIR")
-	if base == "" {
-		base = storageDir + "/logs/"
+// writeRequestLog append data to rotating file <dir>YYYYMMDD.<code> of t. Handle of the file is kept open
+// with its own reference, the reference is released when file of the next day is written
+func writeRequestLog(dir, code string, t time.Time, data []byte) error {
+	filename, err := filepath.Abs(fmt.Sprintf("%s%s.%s", dir, t.Format("20060102"), code))
+	if err != nil {
+		return err
+	}
+
+	requestLogFilesMu.Lock()
+	defer requestLogFilesMu.Unlock()
+
+	f := requestLogFiles[dir+code]
+	if f == nil || f.Filename != filename {
+		next, err := OpenRotatingFile(filename)
+		if err != nil {
+			return err
+		}
+		if f != nil {
+			f.Close()
+		}
+		f = next
+		requestLogFiles[dir+code] = f
 	}
-	return newFileResultLogger(base)
+
+	_, err = f.Write(data)
+	return err
 }
 
-// StoreRequestResponse function for storing request and response into file
+// StoreRequestResponse function for storing request and response into result logger of LOG_DIR (see GetResultLogger)
 // code string code of file
 // req []byte request json byte data
 // res []byte response json byte data
 func StoreRequestResponse(code string, req []byte, res []byte) string {
-	// set data to save/append into log
-	data := "REQUEST: " + string(req[:]) + " RESPONSE: " + string(res[:]) + "\n"
-
 	var fileLogger ResultLogger
 	fileLogger = GetResultLogger()
 
@@ -295,5 +295,10 @@ func StoreRequestResponse(code string, req []byte, res []byte) string {
 		return ""
 	}
 
-	return fileLogger.RequestResponse(code, data)
+	return fileLogger.RequestResponse(code, formatRequestResponse(req, res))
+}
+
+// formatRequestResponse data of request and response to save/append into log
+func formatRequestResponse(req []byte, res []byte) string {
+	return "REQUEST: " + string(req[:]) + " RESPONSE: " + string(res[:]) + "\n"
 }
@@ -0,0 +1,229 @@
+package golib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DropPolicy behaviour of Logger when its queue is full
+type DropPolicy int
+
+const (
+	// DropNewest discard entry which is being logged when queue is full
+	DropNewest DropPolicy = iota
+	// DropOldest discard oldest queued entry to make room for entry which is being logged
+	DropOldest
+	// Block wait until queue has room, caller is blocked
+	Block
+)
+
+var (
+	// LoggerQueueSize default queue size of Logger
+	LoggerQueueSize = 1024
+	// TraceIDFunc get trace id from context, set by package tracer (tracer.GetTraceID) when it is imported
+	TraceIDFunc func(ctx context.Context) string
+	// DefaultLogger logger used by Log and LogError
+	DefaultLogger = NewLogger(LoggerQueueSize, DropNewest)
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID return copy of context with request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext get request id from context, return empty string if not set
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// logEntry queued log entry, flushed is closed by worker when entry is a flush marker
+type logEntry struct {
+	level   Level
+	time    time.Time
+	message string
+	fields  log.Fields
+	flushed chan struct{}
+}
+
+// Logger structured logger which write entries in order through bounded asynchronous queue
+type Logger struct {
+	// Base logrus logger to write entries, standard logger is used if nil
+	Base *log.Logger
+
+	policy  DropPolicy
+	queue   chan *logEntry
+	evicted chan *logEntry
+	once    sync.Once
+	dropped uint64
+}
+
+// NewLogger create logger with queue size and drop policy, worker is started on first entry
+func NewLogger(queueSize int, policy DropPolicy) *Logger {
+	if queueSize <= 0 {
+		queueSize = LoggerQueueSize
+	}
+	return &Logger{policy: policy, queue: make(chan *logEntry, queueSize), evicted: make(chan *logEntry)}
+}
+
+// Debug log message with debug level
+func (l *Logger) Debug(ctx context.Context, message string, fields ...map[string]interface{}) {
+	l.Log(ctx, DebugLevel, message, fields...)
+}
+
+// Info log message with info level
+func (l *Logger) Info(ctx context.Context, message string, fields ...map[string]interface{}) {
+	l.Log(ctx, InfoLevel, message, fields...)
+}
+
+// Warn log message with warning level
+func (l *Logger) Warn(ctx context.Context, message string, fields ...map[string]interface{}) {
+	l.Log(ctx, WarnLevel, message, fields...)
+}
+
+// Error log message with error level
+func (l *Logger) Error(ctx context.Context, message string, fields ...map[string]interface{}) {
+	l.Log(ctx, ErrorLevel, message, fields...)
+}
+
+// Log queue message with level, topic, server_env, trace_id and request_id from context (can be nil) are added to fields
+func (l *Logger) Log(ctx context.Context, level Level, message string, fields ...map[string]interface{}) {
+	entry := &logEntry{
+		level:   level,
+		time:    time.Now(),
+		message: message,
+		fields:  log.Fields{"topic": TOPIC, "server_env": Env},
+	}
+
+	if ctx != nil {
+		if TraceIDFunc != nil {
+			if traceID := TraceIDFunc(ctx); traceID != "" {
+				entry.fields["trace_id"] = traceID
+			}
+		}
+		if requestID := RequestIDFromContext(ctx); requestID != "" {
+			entry.fields["request_id"] = requestID
+		}
+	}
+
+	for _, m := range fields {
+		for k, v := range m {
+			entry.fields[k] = v
+		}
+	}
+
+	l.enqueue(entry)
+}
+
+// Flush wait until all entries queued before Flush is called are written or context is done
+func (l *Logger) Flush(ctx context.Context) error {
+	l.once.Do(l.start)
+
+	marker := &logEntry{flushed: make(chan struct{})}
+	select {
+	case l.queue <- marker:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-marker.flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped number of entries discarded because queue was full
+func (l *Logger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+func (l *Logger) enqueue(entry *logEntry) {
+	l.once.Do(l.start)
+
+	switch l.policy {
+	case Block:
+		l.queue <- entry
+		return
+	case DropOldest:
+		// only older entries are evicted, evicted flush marker is handed to worker which closes it
+		// after writing entry taken before it, so it isn't queued again after entry
+		for !l.offer(entry) {
+			if old := l.evict(); old != nil && old.flushed != nil {
+				go func() { l.evicted <- old }()
+			}
+		}
+	default:
+		if !l.offer(entry) {
+			atomic.AddUint64(&l.dropped, 1)
+		}
+	}
+}
+
+// offer queue entry without blocking, return false if queue is full
+func (l *Logger) offer(entry *logEntry) bool {
+	select {
+	case l.queue <- entry:
+		return true
+	default:
+		return false
+	}
+}
+
+// evict remove oldest queued entry, it is counted as dropped unless it is a flush marker
+func (l *Logger) evict() *logEntry {
+	select {
+	case old := <-l.queue:
+		if old.flushed == nil {
+			atomic.AddUint64(&l.dropped, 1)
+		}
+		return old
+	default:
+		return nil
+	}
+}
+
+func (l *Logger) start() {
+	go func() {
+		for {
+			select {
+			case marker := <-l.evicted:
+				close(marker.flushed)
+			case entry := <-l.queue:
+				if entry.flushed != nil {
+					close(entry.flushed)
+					continue
+				}
+				l.write(entry)
+			}
+		}
+	}()
+}
+
+// write entry to base logger, panic of PanicLevel entry is recovered so worker keeps running
+func (l *Logger) write(entry *logEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println(r)
+		}
+	}()
+
+	base := l.Base
+	if base == nil {
+		base = log.StandardLogger()
+	}
+
+	e := base.WithFields(entry.fields).WithTime(entry.time)
+	e.Log(log.Level(entry.level), entry.message)
+	if entry.level == FatalLevel {
+		base.Exit(1)
+	}
+}
@@ -0,0 +1,226 @@
+package golib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingWriter block every write until release is closed
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func newTestLogger(queueSize int, policy DropPolicy) (*Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(queueSize, policy)
+	l.Base = log.New()
+	l.Base.SetOutput(buf)
+	l.Base.SetFormatter(&log.JSONFormatter{})
+	l.Base.SetLevel(log.TraceLevel)
+	return l, buf
+}
+
+func decodeLogLines(t *testing.T, s string) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if line == "" {
+			continue
+		}
+		m := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal([]byte(line), &m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestLogger(t *testing.T) {
+	TraceIDFunc = func(ctx context.Context) string { return "trace-1" }
+	defer func() { TraceIDFunc = nil }()
+
+	t.Run("Testcase #1: Positive, entries are written in order with context fields", func(t *testing.T) {
+		l, buf := newTestLogger(10, Block)
+		ctx := WithRequestID(context.Background(), "req-1")
+
+		l.Debug(ctx, "debug", map[string]interface{}{"order_id": 1})
+		l.Info(ctx, "info")
+		l.Warn(ctx, "warn")
+		l.Error(ctx, "error")
+		assert.NoError(t, l.Flush(context.Background()))
+
+		lines := decodeLogLines(t, buf.String())
+		assert.Len(t, lines, 4)
+		for i, level := range []string{"debug", "info", "warning", "error"} {
+			assert.Equal(t, level, lines[i]["level"])
+			assert.Equal(t, "trace-1", lines[i]["trace_id"])
+			assert.Equal(t, "req-1", lines[i]["request_id"])
+			assert.Contains(t, lines[i], "topic")
+			assert.Contains(t, lines[i], "server_env")
+		}
+		assert.Equal(t, float64(1), lines[0]["order_id"])
+	})
+
+	t.Run("Testcase #2: Positive, nil context has no trace and request id", func(t *testing.T) {
+		l, buf := newTestLogger(10, Block)
+		l.Info(nil, "info")
+		assert.NoError(t, l.Flush(context.Background()))
+
+		lines := decodeLogLines(t, buf.String())
+		assert.Len(t, lines, 1)
+		assert.NotContains(t, lines[0], "trace_id")
+		assert.NotContains(t, lines[0], "request_id")
+	})
+
+	t.Run("Testcase #3: Positive, panic level entry doesn't stop worker", func(t *testing.T) {
+		l, buf := newTestLogger(10, Block)
+		l.Log(nil, PanicLevel, "panic")
+		l.Info(nil, "after panic")
+		assert.NoError(t, l.Flush(context.Background()))
+
+		lines := decodeLogLines(t, buf.String())
+		assert.Len(t, lines, 2)
+		assert.Equal(t, "after panic", lines[1]["msg"])
+	})
+}
+
+func TestLoggerDropPolicy(t *testing.T) {
+	newBlockedLogger := func(policy DropPolicy) (*Logger, *blockingWriter) {
+		w := &blockingWriter{release: make(chan struct{})}
+		l, _ := newTestLogger(2, policy)
+		l.Base.SetOutput(w)
+
+		// first entry is taken by worker which is blocked on writing it
+		l.Info(nil, "0")
+		for len(l.queue) != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		return l, w
+	}
+
+	t.Run("Testcase #1: Positive, drop newest", func(t *testing.T) {
+		l, w := newBlockedLogger(DropNewest)
+		for _, msg := range []string{"1", "2", "3", "4"} {
+			l.Info(nil, msg)
+		}
+		close(w.release)
+		assert.NoError(t, l.Flush(context.Background()))
+
+		assert.Equal(t, uint64(2), l.Dropped())
+		var messages []interface{}
+		for _, line := range decodeLogLines(t, w.String()) {
+			messages = append(messages, line["msg"])
+		}
+		assert.Equal(t, []interface{}{"0", "1", "2"}, messages)
+	})
+
+	t.Run("Testcase #2: Positive, drop oldest", func(t *testing.T) {
+		l, w := newBlockedLogger(DropOldest)
+		for _, msg := range []string{"1", "2", "3", "4"} {
+			l.Info(nil, msg)
+		}
+		close(w.release)
+		assert.NoError(t, l.Flush(context.Background()))
+
+		assert.Equal(t, uint64(2), l.Dropped())
+		var messages []interface{}
+		for _, line := range decodeLogLines(t, w.String()) {
+			messages = append(messages, line["msg"])
+		}
+		assert.Equal(t, []interface{}{"0", "3", "4"}, messages)
+	})
+
+	t.Run("Testcase #3: Positive, flush races full queue with drop oldest", func(t *testing.T) {
+		l, w := newBlockedLogger(DropOldest)
+
+		flushed := make(chan error)
+		go func() { flushed <- l.Flush(context.Background()) }()
+		for len(l.queue) != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		// flush marker is evicted from full queue while worker is still writing "0"
+		for _, msg := range []string{"1", "2", "3"} {
+			l.Info(nil, msg)
+		}
+		select {
+		case <-flushed:
+			t.Fatal("flush returned before entry taken by worker is written")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(w.release)
+		assert.NoError(t, <-flushed)
+		lines := decodeLogLines(t, w.String())
+		assert.NotEmpty(t, lines)
+		assert.Equal(t, "0", lines[0]["msg"])
+	})
+
+	t.Run("Testcase #4: Positive, drop oldest keep new entry with pending flush", func(t *testing.T) {
+		w := &blockingWriter{release: make(chan struct{})}
+		l, _ := newTestLogger(1, DropOldest)
+		l.Base.SetOutput(w)
+		l.Info(nil, "0")
+		for len(l.queue) != 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		flushed := make(chan error)
+		go func() { flushed <- l.Flush(context.Background()) }()
+		for len(l.queue) != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		// flush marker fill the queue, so it is evicted for "1" which is kept
+		l.Info(nil, "1")
+		close(w.release)
+		assert.NoError(t, <-flushed)
+		assert.NoError(t, l.Flush(context.Background()))
+
+		assert.Equal(t, uint64(0), l.Dropped())
+		var messages []interface{}
+		for _, line := range decodeLogLines(t, w.String()) {
+			messages = append(messages, line["msg"])
+		}
+		assert.Equal(t, []interface{}{"0", "1"}, messages)
+	})
+
+	t.Run("Testcase #5: Negative, flush timeout", func(t *testing.T) {
+		l, w := newBlockedLogger(Block)
+		defer close(w.release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, l.Flush(ctx))
+	})
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	t.Run("Testcase #1: Positive", func(t *testing.T) {
+		assert.Equal(t, "req-1", RequestIDFromContext(WithRequestID(context.Background(), "req-1")))
+	})
+	t.Run("Testcase #2: Negative, not set", func(t *testing.T) {
+		assert.Equal(t, "", RequestIDFromContext(context.Background()))
+	})
+}
@@ -0,0 +1,380 @@
+package golib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LogFormat format of log entry
+type LogFormat string
+
+const (
+	// LogFormatJSON one JSON object per entry
+	LogFormatJSON LogFormat = "json"
+	// LogFormatLogfmt key=value pairs per entry without color
+	LogFormatLogfmt LogFormat = "logfmt"
+	// LogFormatText human readable text, colored when output is terminal
+	LogFormatText LogFormat = "text"
+)
+
+// LogOutputType destination of log entry
+type LogOutputType string
+
+const (
+	// LogOutputStdout write entry to standard output
+	LogOutputStdout LogOutputType = "stdout"
+	// LogOutputStderr write entry to standard error
+	LogOutputStderr LogOutputType = "stderr"
+	// LogOutputFile append entry to file in Address, file is reopened on SIGHUP (see OpenRotatingFile)
+	LogOutputFile LogOutputType = "file"
+	// LogOutputSyslog send entry to syslog daemon over Network (unix, udp or tcp) in Address
+	LogOutputSyslog LogOutputType = "syslog"
+	// LogOutputJournald send entry to journald native socket in Address
+	LogOutputJournald LogOutputType = "journald"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+var (
+	// DefaultLoggerOptions options used by InitLogger, it write JSON entry to standard error.
+	// Syslog and journald are opt-in through InitLoggerWithOptions
+	DefaultLoggerOptions = LoggerOptions{
+		Format:  LogFormatJSON,
+		Level:   "info",
+		Outputs: []LogOutput{{Type: LogOutputStderr}},
+	}
+
+	syslogLocalAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+	loggerMu      sync.Mutex
+	loggerClosers = make(map[*log.Logger][]io.Closer)
+)
+
+// LoggerOptions options of InitLoggerWithOptions
+type LoggerOptions struct {
+	// Format format of entry, JSON if empty
+	Format LogFormat
+	// Level minimum level of entry (trace, debug, info, warning, error, fatal or panic), info if empty
+	Level string
+	// Outputs destination of entry, standard error if empty
+	Outputs []LogOutput
+	// Hostname hostname sent to syslog, os.Hostname() if empty
+	Hostname string
+}
+
+// LogOutput destination of log entry
+type LogOutput struct {
+	Type LogOutputType
+	// Network network of syslog: unix (default), udp or tcp
+	Network string
+	// Address file path, syslog address or journald socket path, local default is used if empty
+	Address string
+}
+
+// configureLogger replace formatter, level, output and hooks of logger, previous file and connection are closed
+func configureLogger(logger *log.Logger, opts LoggerOptions) error {
+	formatter, err := newLogFormatter(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	level := log.InfoLevel
+	if opts.Level != "" {
+		if level, err = log.ParseLevel(opts.Level); err != nil {
+			return err
+		}
+	}
+
+	hostname := opts.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	outputs := opts.Outputs
+	if len(outputs) == 0 {
+		outputs = []LogOutput{{Type: LogOutputStderr}}
+	}
+
+	var writers []io.Writer
+	var closers []io.Closer
+	hooks := make(log.LevelHooks)
+	errs := NewMultiError()
+	for _, output := range outputs {
+		switch output.Type {
+		case LogOutputStdout:
+			writers = append(writers, os.Stdout)
+		case LogOutputStderr:
+			writers = append(writers, os.Stderr)
+		case LogOutputFile:
+			f, err := OpenRotatingFile(output.Address)
+			if err != nil {
+				errs.Append(string(output.Type), err)
+				continue
+			}
+			writers = append(writers, f)
+			closers = append(closers, f)
+		case LogOutputSyslog:
+			hook, err := newSyslogHook(output.Network, output.Address, LogTag, hostname, formatter)
+			if err != nil {
+				errs.Append(string(output.Type), err)
+				continue
+			}
+			hooks.Add(hook)
+			closers = append(closers, hook)
+		case LogOutputJournald:
+			hook, err := newJournaldHook(output.Address, LogTag)
+			if err != nil {
+				errs.Append(string(output.Type), err)
+				continue
+			}
+			hooks.Add(hook)
+			closers = append(closers, hook)
+		default:
+			errs.Append(string(output.Type), fmt.Errorf("unsupported log output '%s'", output.Type))
+		}
+	}
+
+	var out io.Writer = ioutil.Discard
+	if len(writers) == 1 {
+		out = writers[0]
+	} else if len(writers) > 1 {
+		out = io.MultiWriter(writers...)
+	}
+
+	loggerMu.Lock()
+	logger.SetFormatter(formatter)
+	logger.SetLevel(level)
+	logger.SetOutput(out)
+	logger.ReplaceHooks(hooks)
+	previous := loggerClosers[logger]
+	loggerClosers[logger] = closers
+	loggerMu.Unlock()
+
+	for _, c := range previous {
+		c.Close()
+	}
+
+	if errs.HasError() {
+		return errs
+	}
+	return nil
+}
+
+func newLogFormatter(format LogFormat) (log.Formatter, error) {
+	switch format {
+	case "", LogFormatJSON:
+		return &log.JSONFormatter{}, nil
+	case LogFormatLogfmt:
+		return &log.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	case LogFormatText:
+		return &log.TextFormatter{FullTimestamp: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported log format '%s'", format)
+}
+
+// syslogSeverity syslog severity of logrus level
+func syslogSeverity(level log.Level) int {
+	switch level {
+	case log.PanicLevel:
+		return 0 // emerg
+	case log.FatalLevel:
+		return 2 // crit
+	case log.ErrorLevel:
+		return 3 // err
+	case log.WarnLevel:
+		return 4 // warning
+	case log.InfoLevel:
+		return 6 // info
+	}
+	return 7 // debug
+}
+
+// syslogHook send entry formatted with formatter as RFC 3164 message, connection is redialed once when write failed
+type syslogHook struct {
+	mu        sync.Mutex
+	network   string
+	address   string
+	tag       string
+	hostname  string
+	formatter log.Formatter
+	conn      net.Conn
+}
+
+func newSyslogHook(network, address, tag, hostname string, formatter log.Formatter) (*syslogHook, error) {
+	if tag == "" {
+		tag = os.Args[0]
+		if i := strings.LastIndex(tag, "/"); i >= 0 {
+			tag = tag[i+1:]
+		}
+	}
+	h := &syslogHook{network: network, address: address, tag: tag, hostname: hostname, formatter: formatter}
+	if err := h.connect(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *syslogHook) connect() (err error) {
+	if h.conn != nil {
+		h.conn.Close()
+		h.conn = nil
+	}
+
+	if h.network == "" || h.network == "unix" {
+		addresses := syslogLocalAddresses
+		if h.address != "" {
+			addresses = []string{h.address}
+		}
+		for _, address := range addresses {
+			for _, network := range []string{"unixgram", "unix"} {
+				if h.conn, err = net.Dial(network, address); err == nil {
+					return nil
+				}
+			}
+		}
+		return err
+	}
+
+	h.conn, err = net.DialTimeout(h.network, h.address, 5*time.Second)
+	return err
+}
+
+// Levels all levels are sent to syslog
+func (h *syslogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire send entry to syslog
+func (h *syslogHook) Fire(entry *log.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	const facilityUser = 1
+	msg := fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+		facilityUser*8+syslogSeverity(entry.Level), entry.Time.Format(time.Stamp), h.hostname, h.tag, os.Getpid(),
+		strings.TrimRight(string(b), "\n"))
+	if strings.HasPrefix(h.network, "tcp") {
+		msg += "\n" // non-transparent framing
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn != nil {
+		if _, err = io.WriteString(h.conn, msg); err == nil {
+			return nil
+		}
+	}
+	if err = h.connect(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(h.conn, msg)
+	return err
+}
+
+// Close close syslog connection
+func (h *syslogHook) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// journaldHook send entry to journald with native protocol, entry fields are sent as uppercase journal fields
+type journaldHook struct {
+	tag  string
+	conn net.Conn
+}
+
+func newJournaldHook(address, tag string) (*journaldHook, error) {
+	if address == "" {
+		address = journaldSocket
+	}
+	conn, err := net.Dial("unixgram", address)
+	if err != nil {
+		return nil, err
+	}
+	return &journaldHook{tag: tag, conn: conn}, nil
+}
+
+// Levels all levels are sent to journald
+func (h *journaldHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire send entry to journald
+func (h *journaldHook) Fire(entry *log.Entry) error {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", entry.Message)
+	writeJournalField(&buf, "PRIORITY", fmt.Sprint(syslogSeverity(entry.Level)))
+	if h.tag != "" {
+		writeJournalField(&buf, "SYSLOG_IDENTIFIER", h.tag)
+	}
+	for k, v := range entry.Data {
+		name := journalFieldName(k)
+		if name == "" {
+			continue
+		}
+		writeJournalField(&buf, name, toJournalValue(v))
+	}
+
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+// Close close journald socket
+func (h *journaldHook) Close() error {
+	return h.conn.Close()
+}
+
+// journalFieldName uppercase key with character other than A-Z, 0-9 and _ replaced, field can't start with _ or digit
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func toJournalValue(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+// writeJournalField write NAME=value line, value with newline is written as NAME, little endian 64 bit length and value
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
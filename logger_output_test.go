@@ -0,0 +1,182 @@
+package golib
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golib-logger")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		format  LogFormat
+		level   string
+		want    string
+		notWant string
+	}{
+		{name: "Testcase #1: Positive, json", format: LogFormatJSON, want: `"msg":"info message"`, notWant: "debug message"},
+		{name: "Testcase #2: Positive, logfmt", format: LogFormatLogfmt, level: "debug", want: `msg="debug message"`},
+		{name: "Testcase #3: Positive, text", format: LogFormatText, want: `msg="info message"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, string(tt.format)+".log")
+			logger := log.New()
+			err := configureLogger(logger, LoggerOptions{
+				Format:  tt.format,
+				Level:   tt.level,
+				Outputs: []LogOutput{{Type: LogOutputFile, Address: file}},
+			})
+			assert.NoError(t, err)
+
+			logger.Debug("debug message")
+			logger.Info("info message")
+
+			b, _ := ioutil.ReadFile(file)
+			assert.Contains(t, string(b), tt.want)
+			if tt.notWant != "" {
+				assert.NotContains(t, string(b), tt.notWant)
+			}
+		})
+	}
+
+	t.Run("Testcase #4: Negative, invalid format", func(t *testing.T) {
+		assert.Error(t, configureLogger(log.New(), LoggerOptions{Format: "xml"}))
+	})
+
+	t.Run("Testcase #5: Negative, invalid level", func(t *testing.T) {
+		assert.Error(t, configureLogger(log.New(), LoggerOptions{Level: "verbose"}))
+	})
+
+	t.Run("Testcase #6: Negative, failed output is skipped", func(t *testing.T) {
+		file := filepath.Join(dir, "skipped.log")
+		logger := log.New()
+		err := configureLogger(logger, LoggerOptions{Outputs: []LogOutput{
+			{Type: LogOutputFile, Address: filepath.Join(dir, "missing", "app.log")},
+			{Type: "kafka"},
+			{Type: LogOutputFile, Address: file},
+		}})
+		assert.Error(t, err)
+		assert.Len(t, err.(*MultiError).Errors(), 2)
+
+		logger.Info("info message")
+		b, _ := ioutil.ReadFile(file)
+		assert.Contains(t, string(b), "info message")
+	})
+}
+
+func TestSyslogOutput(t *testing.T) {
+	pid := os.Getpid()
+
+	t.Run("Testcase #1: Positive, udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		logger := log.New()
+		err = configureLogger(logger, LoggerOptions{
+			Hostname: "host-1",
+			Outputs:  []LogOutput{{Type: LogOutputSyslog, Network: "udp", Address: conn.LocalAddr().String()}},
+		})
+		assert.NoError(t, err)
+		logger.Warn("warn message")
+
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		assert.NoError(t, err)
+		msg := string(buf[:n])
+		assert.True(t, strings.HasPrefix(msg, "<12>"), msg)
+		assert.Contains(t, msg, " host-1 ")
+		assert.Contains(t, msg, "["+strconv.Itoa(pid)+"]: ")
+		assert.Contains(t, msg, `"msg":"warn message"`)
+	})
+
+	t.Run("Testcase #2: Positive, tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer ln.Close()
+
+		lines := make(chan string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			lines <- line
+		}()
+
+		logger := log.New()
+		err = configureLogger(logger, LoggerOptions{
+			Format:  LogFormatLogfmt,
+			Outputs: []LogOutput{{Type: LogOutputSyslog, Network: "tcp", Address: ln.Addr().String()}},
+		})
+		assert.NoError(t, err)
+		logger.Error("error message")
+
+		select {
+		case line := <-lines:
+			assert.True(t, strings.HasPrefix(line, "<11>"), line)
+			assert.Contains(t, line, `msg="error message"`)
+			assert.True(t, strings.HasSuffix(line, "\n"))
+		case <-time.After(time.Second):
+			t.Fatal("syslog message is not received")
+		}
+		configureLogger(logger, LoggerOptions{}) // close connection
+	})
+
+	t.Run("Testcase #3: Negative, syslog unreachable", func(t *testing.T) {
+		err := configureLogger(log.New(), LoggerOptions{
+			Outputs: []LogOutput{{Type: LogOutputSyslog, Network: "unix", Address: "/nonexistent/log"}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestJournaldOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golib-journald")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenPacket("unixgram", socket)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	logger := log.New()
+	err = configureLogger(logger, LoggerOptions{Outputs: []LogOutput{{Type: LogOutputJournald, Address: socket}}})
+	assert.NoError(t, err)
+	logger.WithFields(log.Fields{"request-id": "req-1", "stack": "line 1\nline 2"}).Info("info message")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	msg := string(buf[:n])
+	assert.Contains(t, msg, "MESSAGE=info message\n")
+	assert.Contains(t, msg, "PRIORITY=6\n")
+	assert.Contains(t, msg, "REQUEST_ID=req-1\n")
+	assert.Contains(t, msg, "STACK\n\x0d\x00\x00\x00\x00\x00\x00\x00line 1\nline 2\n")
+}
+
+func TestJournalFieldName(t *testing.T) {
+	assert.Equal(t, "TRACE_ID", journalFieldName("trace_id"))
+	assert.Equal(t, "HTTP_STATUS", journalFieldName("http.status"))
+	assert.Equal(t, "ID", journalFieldName("_id"))
+	assert.Equal(t, "", journalFieldName("__"))
+}
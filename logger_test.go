@@ -1,12 +1,18 @@
 package golib
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -64,6 +70,25 @@ func TestInitLogger(t *testing.T) {
 		assert.Equal(t, "test", LogTag)
 		assert.Equal(t, "test", Env)
 	})
+
+	t.Run("InitLogger with options", func(t *testing.T) {
+		file := filepath.Join(os.TempDir(), "golib-init-logger.log")
+		os.Remove(file)
+		defer os.Remove(file)
+		defer InitLogger("test", "test", "test")
+
+		err := InitLoggerWithOptions("test", "test", "test", LoggerOptions{
+			Format:  LogFormatLogfmt,
+			Level:   "warning",
+			Outputs: []LogOutput{{Type: LogOutputFile, Address: file}},
+		})
+		assert.NoError(t, err)
+
+		log.Info("info message")
+		log.Warn("warn message")
+		b, _ := ioutil.ReadFile(file)
+		assert.Regexp(t, `^time="[^"]+" level=warning msg="warn message"\n$`, string(b))
+	})
 }
 
 func TestLogContext(t *testing.T) {
@@ -142,6 +167,7 @@ func TestLog(t *testing.T) {
 			Log(tc.level, tc.message, tc.context, tc.scope, tc.tags)
 		})
 	}
+	assert.NoError(t, DefaultLogger.Flush(context.Background()))
 }
 
 func TestLogError(t *testing.T) {
@@ -150,9 +176,10 @@ func TestLogError(t *testing.T) {
 
 	t.Parallel()
 
-	t.Run("LOG ERROR", func(*testing.T) {
+	t.Run("LOG ERROR", func(t *testing.T) {
 		msg := "test"
 		LogError(err, ctx, msg)
+		assert.NoError(t, DefaultLogger.Flush(context.Background()))
 	})
 }
 
@@ -227,6 +254,51 @@ func TestFileResultLoggerRequestResponse(t *testing.T) {
 		s := f.RequestResponse("test", "test")
 		assert.Equal(t, "", s)
 	})
+
+	t.Run("SUCCESS RequestResponse", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "golib-storage")
+		defer os.RemoveAll(dir)
+		defer func(old string) { storageDir = old }(storageDir)
+		storageDir = dir
+
+		f := &FileResultLogger{}
+		f.RequestResponse("test", "REQUEST: a RESPONSE: b\n")
+		f.RequestResponse("test", "REQUEST: c RESPONSE: d\n")
+		assert.NoError(t, f.LastError())
+
+		b, err := ioutil.ReadFile(dir + "/logs/" + time.Now().Format("20060102") + ".test")
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		assert.Len(t, lines, 2)
+		assert.Regexp(t, `^\d{2}:\d{2}:\d{2} : REQUEST: a RESPONSE: b$`, lines[0])
+		assert.Regexp(t, `^\d{2}:\d{2}:\d{2} : REQUEST: c RESPONSE: d$`, lines[1])
+	})
+
+	t.Run("SUCCESS RequestResponse after other holder close shared file", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "golib-storage")
+		defer os.RemoveAll(dir)
+		today := time.Now()
+		name := dir + "/" + today.Format("20060102") + ".test"
+
+		rf, err := OpenRotatingFile(name)
+		assert.NoError(t, err)
+		assert.NoError(t, writeRequestLog(dir+"/", "test", today, []byte("a\n")))
+		assert.NoError(t, rf.Close())
+		assert.NoError(t, writeRequestLog(dir+"/", "test", today, []byte("b\n")))
+
+		// reference of previous day is released
+		tomorrow := today.Add(24 * time.Hour)
+		assert.NoError(t, writeRequestLog(dir+"/", "test", tomorrow, []byte("c\n")))
+		rotatingFilesMu.Lock()
+		_, ok := rotatingFiles[name]
+		rotatingFilesMu.Unlock()
+		assert.False(t, ok)
+
+		b, _ := ioutil.ReadFile(name)
+		assert.Equal(t, "a\nb\n", string(b))
+		b, _ = ioutil.ReadFile(dir + "/" + tomorrow.Format("20060102") + ".test")
+		assert.Equal(t, "c\n", string(b))
+	})
 }
 
 func TestGetResultLogger(t *testing.T) {
@@ -1,68 +1,259 @@
 package golib
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
-// MultiError model
+const (
+	// ErrorCodeRequired error code for empty required value
+	ErrorCodeRequired = "required"
+	// ErrorCodeInvalidFormat error code for value which cannot be parsed to target type
+	ErrorCodeInvalidFormat = "invalid_format"
+	// ErrorCodeNotAllowed error code for value which is not in whitelist (ex: unknown field in JSON:API query)
+	ErrorCodeNotAllowed = "not_allowed"
+)
+
+// ErrorItem single error in MultiError with key, optional machine readable code and JSON pointer path (RFC 6901).
+// Params is parameters of the failed rule (ex: {"param": "3"} for "min=3") used to render localized message.
+// Parameter is set instead of Path when error is caused by query parameter (ex: "filter[price][gt]")
+type ErrorItem struct {
+	Key       string
+	Code      string
+	Path      string
+	Parameter string
+	Err       error
+	Params    map[string]interface{}
+}
+
+// Error implement error from ErrorItem
+func (e *ErrorItem) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap return original error
+func (e *ErrorItem) Unwrap() error {
+	return e.Err
+}
+
+// errorItemDocument marshaled form of ErrorItem
+type errorItemDocument struct {
+	Key       string `json:"key" xml:"key,attr"`
+	Code      string `json:"code,omitempty" xml:"code,attr,omitempty"`
+	Path      string `json:"path,omitempty" xml:"path,attr,omitempty"`
+	Parameter string `json:"parameter,omitempty" xml:"parameter,attr,omitempty"`
+	Message   string `json:"message" xml:",chardata"`
+}
+
+// MultiError model, safe to be used concurrently by multiple goroutines
 type MultiError struct {
-	errs map[string]string
+	mu    sync.RWMutex
+	items []*ErrorItem
 }
 
 // NewMultiError constructor
 func NewMultiError() *MultiError {
-	return &MultiError{errs: make(map[string]string)}
+	return &MultiError{}
 }
 
 // Append error to multierror
 func (m *MultiError) Append(key string, err error) {
+	m.AppendCode(key, "", err)
+}
+
+// AppendCode append error with machine readable code (ex: ErrorCodeRequired) to multierror
+func (m *MultiError) AppendCode(key, code string, err error) {
 	if err != nil {
-		if val, ok := m.errs[key]; ok {
-			m.errs[key] = fmt.Sprintf("%s; %s", val, err.Error())
-		} else {
-			m.errs[key] = err.Error()
-		}
+		m.AppendItem(&ErrorItem{Key: key, Code: code, Err: err})
 	}
 }
 
+// AppendItem append error item to multierror, path is generated from dotted key if both path and parameter are empty
+// (ex: "address.city" to "/address/city")
+func (m *MultiError) AppendItem(item *ErrorItem) {
+	if item == nil || item.Err == nil {
+		return
+	}
+	if item.Path == "" && item.Parameter == "" {
+		item.Path = KeyToJSONPointer(item.Key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = append(m.items, item)
+}
+
 // HasError check if err is exist
 func (m *MultiError) HasError() bool {
-	return len(m.errs) != 0
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.items) != 0
 }
 
 // Clear make empty list of errors
 func (m *MultiError) Clear() {
-	m.errs = map[string]string{}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = nil
 }
 
-// ToMap return list map of error
+// Errors return list of error item in insertion order
+func (m *MultiError) Errors() []*ErrorItem {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*ErrorItem(nil), m.items...)
+}
+
+// Keys return list of unique error key in insertion order
+func (m *MultiError) Keys() []string {
+	var keys []string
+	exist := make(map[string]bool)
+	for _, item := range m.Errors() {
+		if !exist[item.Key] {
+			exist[item.Key] = true
+			keys = append(keys, item.Key)
+		}
+	}
+	return keys
+}
+
+// Get return list of error message with key in insertion order
+func (m *MultiError) Get(key string) (messages []string) {
+	for _, item := range m.Errors() {
+		if item.Key == key {
+			messages = append(messages, item.Err.Error())
+		}
+	}
+	return
+}
+
+// ToMap return list map of error, multiple error with same key is joined with "; "
 func (m *MultiError) ToMap() map[string]string {
-	return m.errs
+	errs := make(map[string]string)
+	for _, item := range m.Errors() {
+		if val, ok := errs[item.Key]; ok {
+			errs[item.Key] = fmt.Sprintf("%s; %s", val, item.Err.Error())
+		} else {
+			errs[item.Key] = item.Err.Error()
+		}
+	}
+	return errs
 }
 
 // Error implement error from multiError
 func (m *MultiError) Error() string {
+	errs := m.ToMap()
 	var str []string
-	for i, s := range m.errs {
-		str = append(str, fmt.Sprintf("%s: %s", i, s))
+	for _, key := range m.Keys() {
+		str = append(str, fmt.Sprintf("%s: %s", key, errs[key]))
 	}
 	return strings.Join(str, "\n")
 }
 
-// AppendMultiError constructor
+// Unwrap return list of error item, so errors.Is and errors.As can match original error or *ErrorItem
+func (m *MultiError) Unwrap() []error {
+	items := m.Errors()
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = item
+	}
+	return errs
+}
+
+// MarshalJSON implement json.Marshaler, marshal list of error item (key, code, path and message) in insertion order
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	items := m.Errors()
+	docs := make([]errorItemDocument, len(items))
+	for i, item := range items {
+		docs[i] = errorItemDocument{Key: item.Key, Code: item.Code, Path: item.Path, Parameter: item.Parameter, Message: item.Err.Error()}
+	}
+	return json.Marshal(docs)
+}
+
+// UnmarshalJSON implement json.Unmarshaler, rebuild multierror from list of error item
+// or from map of error (ToMap format in ResponseV2 errors)
+func (m *MultiError) UnmarshalJSON(data []byte) error {
+	var docs []errorItemDocument
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var errMap map[string]string
+		if err := json.Unmarshal(trimmed, &errMap); err != nil {
+			return err
+		}
+		for key, message := range errMap {
+			docs = append(docs, errorItemDocument{Key: key, Message: message})
+		}
+		sort.Slice(docs, func(i, j int) bool { return docs[i].Key < docs[j].Key })
+	} else if err := json.Unmarshal(data, &docs); err != nil {
+		return err
+	}
+
+	m.Clear()
+	for _, doc := range docs {
+		m.AppendItem(&ErrorItem{Key: doc.Key, Code: doc.Code, Path: doc.Path, Parameter: doc.Parameter, Err: errors.New(doc.Message)})
+	}
+	return nil
+}
+
+// MarshalXML implement xml.Marshaler, marshal list of error item as <error> element (default root element is <errors>)
+func (m *MultiError) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if start.Name.Local == "" || start.Name.Local == "MultiError" {
+		start.Name = xml.Name{Local: "errors"}
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, item := range m.Errors() {
+		doc := errorItemDocument{Key: item.Key, Code: item.Code, Path: item.Path, Parameter: item.Parameter, Message: item.Err.Error()}
+		if err := e.EncodeElement(doc, xml.StartElement{Name: xml.Name{Local: "error"}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// KeyToJSONPointer convert dotted key to JSON pointer (RFC 6901), ex: "items.0.name" to "/items/0/name"
+func KeyToJSONPointer(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	var pointer strings.Builder
+	for _, token := range strings.Split(key, ".") {
+		token = strings.Replace(token, "~", "~0", -1)
+		token = strings.Replace(token, "/", "~1", -1)
+		pointer.WriteString("/" + token)
+	}
+	return pointer.String()
+}
+
+// AppendMultiError constructor, error in map1 with key exist in map2 is replaced by error in map2
 func AppendMultiError(map1, map2 *MultiError) *MultiError {
-	var mergeMultiError = make(map[string]string)
+	var mergeMultiError = NewMultiError()
 
-	for key, val := range map1.ToMap() {
-		mergeMultiError[key] = val
+	replaced := make(map[string]bool)
+	for _, item := range map2.Errors() {
+		replaced[item.Key] = true
+	}
+
+	for _, item := range map1.Errors() {
+		if !replaced[item.Key] {
+			mergeMultiError.AppendItem(item)
+		}
 	}
 
-	for key, val := range map2.ToMap() {
-		mergeMultiError[key] = val
+	for _, item := range map2.Errors() {
+		mergeMultiError.AppendItem(item)
 	}
 
-	return &MultiError{mergeMultiError}
+	return mergeMultiError
 }
 
 // MultiErrorNotNill function to convert multi error from nil to not nil
@@ -73,3 +264,66 @@ func MultiErrorNotNill(multiError *MultiError) *MultiError {
 
 	return multiError
 }
+
+// MultiErrorGroup run functions concurrently and collect the error of each function into MultiError with its key
+type MultiErrorGroup struct {
+	wg   sync.WaitGroup
+	sem  chan struct{}
+	errs *MultiError
+}
+
+// NewMultiErrorGroup constructor, limit is maximum number of function running at the same time (unlimited if not set)
+func NewMultiErrorGroup(limit ...int) *MultiErrorGroup {
+	g := &MultiErrorGroup{errs: NewMultiError()}
+	if len(limit) > 0 && limit[0] > 0 {
+		g.sem = make(chan struct{}, limit[0])
+	}
+	return g
+}
+
+// Go run function in new goroutine, returned error is collected with key.
+// If function return *MultiError, each error item is collected with key prefix (ex: "address" + "." + "city")
+// and panic in function is collected as error
+func (g *MultiErrorGroup) Go(key string, fn func() error) {
+	g.wg.Add(1)
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				g.errs.Append(key, fmt.Errorf("panic: %v", r))
+			}
+			if g.sem != nil {
+				<-g.sem
+			}
+			g.wg.Done()
+		}()
+
+		err := fn()
+		if multiError, ok := err.(*MultiError); ok {
+			for _, item := range multiError.Errors() {
+				copied := *item
+				switch {
+				case key != "" && item.Key == "":
+					copied.Key = key
+				case key != "":
+					copied.Key = key + "." + item.Key
+				}
+				if key != "" {
+					copied.Path = KeyToJSONPointer(key) + item.Path
+				}
+				g.errs.AppendItem(&copied)
+			}
+			return
+		}
+		g.errs.Append(key, err)
+	}()
+}
+
+// Wait block until all functions have returned, then return collected errors (never nil, check with HasError)
+func (g *MultiErrorGroup) Wait() *MultiError {
+	g.wg.Wait()
+	return g.errs
+}
@@ -1,9 +1,14 @@
 package golib
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -58,10 +63,62 @@ func TestAppendMultiError(t *testing.T) {
 
 	t.Run("APPEND EXISTING KEY", func(t *testing.T) {
 		m := NewMultiError()
-		m.errs["test"] = "test"
+		m.Append("test", errors.New("test"))
 		m.Append("test", errors.New("testing"))
-		assert.Equal(t, "test; testing", m.errs["test"])
+		assert.Equal(t, "test; testing", m.ToMap()["test"])
 	})
+
+	t.Run("REPLACE EXISTING KEY", func(t *testing.T) {
+		m1 := NewMultiError()
+		m1.Append("err1", errors.New("error 11"))
+		m1.Append("err2", errors.New("error 21"))
+		m2 := NewMultiError()
+		m2.Append("err1", errors.New("error 12"))
+
+		got := AppendMultiError(m1, m2)
+		assert.Equal(t, []string{"err2", "err1"}, got.Keys())
+		assert.Equal(t, map[string]string{"err1": "error 12", "err2": "error 21"}, got.ToMap())
+	})
+}
+
+func TestMultiErrorOrderAndItem(t *testing.T) {
+	m := NewMultiError()
+	m.Append("name", errors.New("is required"))
+	m.AppendCode("items.0.sku", ErrorCodeInvalidFormat, errors.New("invalid sku"))
+	m.Append("name", nil)
+	m.AppendItem(&ErrorItem{Key: "a/b~c", Err: errors.New("invalid key")})
+	m.Append("name", errors.New("too short"))
+
+	assert.Equal(t, []string{"name", "items.0.sku", "a/b~c"}, m.Keys())
+	assert.Equal(t, "name: is required; too short\nitems.0.sku: invalid sku\na/b~c: invalid key", m.Error())
+	assert.Equal(t, []string{"is required", "too short"}, m.Get("name"))
+
+	items := m.Errors()
+	assert.Equal(t, 4, len(items))
+	assert.Equal(t, "/name", items[0].Path)
+	assert.Equal(t, ErrorCodeInvalidFormat, items[1].Code)
+	assert.Equal(t, "/items/0/sku", items[1].Path)
+	assert.Equal(t, "/a~1b~0c", items[2].Path)
+}
+
+func TestMultiErrorUnwrap(t *testing.T) {
+	errNotFound := errors.New("not found")
+	m := NewMultiError()
+	m.Append("id", fmt.Errorf("order: %w", errNotFound))
+	m.AppendCode("page", ErrorCodeInvalidFormat, errors.New("invalid page"))
+
+	var err error = m
+	assert.True(t, errors.Is(err, errNotFound))
+	assert.False(t, errors.Is(err, ErrRequired))
+
+	var item *ErrorItem
+	assert.True(t, errors.As(err, &item))
+	assert.Equal(t, "id", item.Key)
+
+	var wrapped error = fmt.Errorf("validate: %w", m)
+	var multiError *MultiError
+	assert.True(t, errors.As(wrapped, &multiError))
+	assert.Equal(t, 2, len(multiError.Unwrap()))
 }
 
 func TestMultiErrorNotNill(t *testing.T) {
@@ -91,7 +148,133 @@ func TestMultiErrorNotNill(t *testing.T) {
 func TestMultiErrorClear(t *testing.T) {
 	t.Run("CLEAR MULTI ERROR", func(t *testing.T) {
 		m := NewMultiError()
+		m.Append("test", errors.New("test"))
 		m.Clear()
-		assert.Equal(t, 0, len(m.errs))
+		assert.False(t, m.HasError())
+		assert.Equal(t, 0, len(m.Errors()))
+	})
+}
+
+func TestMultiErrorConcurrentAppend(t *testing.T) {
+	m := NewMultiError()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Append("field"+strconv.Itoa(i%10), fmt.Errorf("error %d", i))
+			m.HasError()
+			m.ToMap()
+			_ = m.Error()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, len(m.Errors()))
+	assert.Equal(t, 10, len(m.ToMap()))
+}
+
+func TestMultiErrorGroup(t *testing.T) {
+	t.Run("COLLECT ERROR BY KEY", func(t *testing.T) {
+		g := NewMultiErrorGroup()
+		g.Go("name", func() error { return errors.New("is required") })
+		g.Go("email", func() error { return nil })
+		g.Go("address", func() error {
+			m := NewMultiError()
+			m.AppendCode("city", ErrorCodeRequired, ErrRequired)
+			m.Append("", errors.New("invalid address"))
+			return m
+		})
+		g.Go("phone", func() error { panic("unexpected") })
+
+		errs := g.Wait()
+		assert.True(t, errs.HasError())
+		assert.Equal(t, []string{"address", "address.city", "name", "phone"}, sortedKeys(errs.ToMap()))
+		assert.Equal(t, "panic: unexpected", errs.ToMap()["phone"])
+		assert.True(t, errors.Is(errs, ErrRequired))
+	})
+
+	t.Run("KEEP NESTED ITEM PARAMS AND PATH", func(t *testing.T) {
+		g := NewMultiErrorGroup()
+		g.Go("address", func() error {
+			m := NewMultiError()
+			m.AppendItem(&ErrorItem{Key: "city", Code: "min", Path: "/cityName", Err: errors.New("too short"),
+				Params: map[string]interface{}{"min": 3}})
+			return m
+		})
+
+		items := g.Wait().Errors()
+		assert.Len(t, items, 1)
+		assert.Equal(t, "address.city", items[0].Key)
+		assert.Equal(t, "/address/cityName", items[0].Path)
+		assert.Equal(t, "min", items[0].Code)
+		assert.Equal(t, map[string]interface{}{"min": 3}, items[0].Params)
+	})
+
+	t.Run("LIMIT RUNNING FUNCTION", func(t *testing.T) {
+		var running, maxRunning int32
+		g := NewMultiErrorGroup(2)
+		for i := 0; i < 20; i++ {
+			key := "item." + strconv.Itoa(i)
+			g.Go(key, func() error {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+						break
+					}
+				}
+				return errors.New("invalid")
+			})
+		}
+
+		errs := g.Wait()
+		assert.Equal(t, 20, len(errs.Keys()))
+		assert.True(t, atomic.LoadInt32(&maxRunning) <= 2)
+	})
+}
+
+func TestMultiErrorMarshal(t *testing.T) {
+	m := NewMultiError()
+	m.AppendCode("name", ErrorCodeRequired, ErrRequired)
+	m.Append("items.0.sku", errors.New("invalid <sku>"))
+
+	t.Run("JSON ROUND TRIP", func(t *testing.T) {
+		b, err := json.Marshal(m)
+		assert.NoError(t, err)
+		assert.Equal(t, `[{"key":"name","code":"required","path":"/name","message":"is required"},`+
+			`{"key":"items.0.sku","path":"/items/0/sku","message":"invalid \u003csku\u003e"}]`, string(b))
+
+		result := NewMultiError()
+		assert.NoError(t, json.Unmarshal(b, result))
+		assert.Equal(t, m.Error(), result.Error())
+		assert.Equal(t, ErrorCodeRequired, result.Errors()[0].Code)
+	})
+
+	t.Run("JSON FROM ERROR MAP", func(t *testing.T) {
+		var resp struct {
+			Errors *MultiError `json:"errors"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(`{"errors": {"page": "invalid", "limit": "invalid"}}`), &resp))
+		assert.Equal(t, []string{"limit", "page"}, resp.Errors.Keys())
+		assert.Error(t, json.Unmarshal([]byte(`{"errors": "invalid"}`), &resp))
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		b, err := xml.Marshal(m)
+		assert.NoError(t, err)
+		assert.Equal(t, `<errors><error key="name" code="required" path="/name">is required</error>`+
+			`<error key="items.0.sku" path="/items/0/sku">invalid &lt;sku&gt;</error></errors>`, string(b))
+
+		var resp struct {
+			XMLName xml.Name    `xml:"response"`
+			Errors  *MultiError `xml:"validation"`
+		}
+		resp.Errors = m
+		b, err = xml.Marshal(resp)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), `<response><validation><error key="name"`)
 	})
 }
@@ -0,0 +1,222 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Bhinneka/golib"
+)
+
+var (
+	// DefaultLimit limit used when limit is not set in query
+	DefaultLimit = 10
+	// MaxLimit maximum limit, bigger limit from query is clamped to this value
+	MaxLimit = 100
+)
+
+type (
+	// Pagination parameter from query string (page, limit, sort and cursor), can be embedded in other param struct
+	Pagination struct {
+		Page   int    `json:"page" default:"1"`
+		Limit  int    `json:"limit"`
+		Sort   string `json:"sort,omitempty"`
+		Cursor string `json:"cursor,omitempty"`
+	}
+
+	// SortField field and direction of sort parameter
+	SortField struct {
+		Field string
+		Desc  bool
+	}
+
+	// Links pagination links, empty link is not available (ex: prev in first page)
+	Links struct {
+		Self  string `json:"self,omitempty"`
+		First string `json:"first,omitempty"`
+		Prev  string `json:"prev,omitempty"`
+		Next  string `json:"next,omitempty"`
+		Last  string `json:"last,omitempty"`
+	}
+
+	// CursorMeta meta for cursor based pagination
+	CursorMeta struct {
+		Limit      int    `json:"limit"`
+		NextCursor string `json:"nextCursor,omitempty"`
+		PrevCursor string `json:"prevCursor,omitempty"`
+	}
+)
+
+// Parse pagination parameter from url query with ParseFromQueryParam and normalize the value
+func Parse(query url.Values) (*Pagination, error) {
+	p := new(Pagination)
+	if err := golib.ParseFromQueryParam(query, p); err != nil {
+		return nil, err
+	}
+	p.Normalize()
+	return p, nil
+}
+
+// Normalize set page to 1 if less than 1, set limit to DefaultLimit if not set and clamp limit to MaxLimit
+func (p *Pagination) Normalize() {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Limit < 1 {
+		p.Limit = DefaultLimit
+	}
+	if MaxLimit > 0 && p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
+}
+
+// Offset number of record skipped before current page
+func (p *Pagination) Offset() int {
+	if p.Page < 1 {
+		return 0
+	}
+	return (p.Page - 1) * p.Limit
+}
+
+// SortFields parse sort parameter (ex: "-createdAt,name"), "-" prefix is descending order
+func (p *Pagination) SortFields() (fields []SortField) {
+	for _, field := range strings.Split(p.Sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || field == "-" {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			fields = append(fields, SortField{Field: field[1:], Desc: true})
+		} else {
+			fields = append(fields, SortField{Field: strings.TrimPrefix(field, "+")})
+		}
+	}
+	return
+}
+
+// Meta create golib.Meta for response from total records
+func (p *Pagination) Meta(totalRecords int) golib.Meta {
+	return golib.Meta{
+		Page:         p.Page,
+		Limit:        p.Limit,
+		TotalRecords: totalRecords,
+		TotalPages:   TotalPages(totalRecords, p.Limit),
+	}
+}
+
+// CursorMeta create CursorMeta for cursor based pagination response
+func (p *Pagination) CursorMeta(nextCursor, prevCursor string) CursorMeta {
+	return CursorMeta{Limit: p.Limit, NextCursor: nextCursor, PrevCursor: prevCursor}
+}
+
+// TotalPages calculate total pages from total records and limit
+func TotalPages(totalRecords, limit int) int {
+	if limit < 1 || totalRecords < 1 {
+		return 0
+	}
+	return (totalRecords + limit - 1) / limit
+}
+
+// Links create first, prev, next and last link from self link of request (see golib.GetSelfLink)
+func (p *Pagination) Links(req *http.Request, totalRecords int) Links {
+	self := golib.GetSelfLink(req)
+	links := Links{Self: self}
+
+	totalPages := TotalPages(totalRecords, p.Limit)
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	links.First = pageLink(self, 1, p.Limit)
+	links.Last = pageLink(self, totalPages, p.Limit)
+	if p.Page > 1 {
+		prev := p.Page - 1
+		if prev > totalPages {
+			prev = totalPages
+		}
+		links.Prev = pageLink(self, prev, p.Limit)
+	}
+	if p.Page < totalPages {
+		links.Next = pageLink(self, p.Page+1, p.Limit)
+	}
+	return links
+}
+
+// CursorLinks create next and prev link with cursor from self link of request
+func CursorLinks(req *http.Request, nextCursor, prevCursor string) Links {
+	self := golib.GetSelfLink(req)
+	links := Links{Self: self}
+	if nextCursor != "" {
+		links.Next = setQuery(self, map[string]string{"cursor": nextCursor}, "page")
+	}
+	if prevCursor != "" {
+		links.Prev = setQuery(self, map[string]string{"cursor": prevCursor}, "page")
+	}
+	return links
+}
+
+func pageLink(self string, page, limit int) string {
+	return setQuery(self, map[string]string{"page": strconv.Itoa(page), "limit": strconv.Itoa(limit)}, "cursor")
+}
+
+func setQuery(link string, values map[string]string, removeKeys ...string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	query := u.Query()
+	for _, key := range removeKeys {
+		query.Del(key)
+	}
+	for key, val := range values {
+		query.Set(key, val)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Header format links to Link header value (RFC 5988), ex: <http://host/path?page=2>; rel="next"
+func (l Links) Header() string {
+	var links []string
+	for _, link := range []struct{ rel, url string }{
+		{"first", l.First}, {"prev", l.Prev}, {"next", l.Next}, {"last", l.Last},
+	} {
+		if link.url != "" {
+			links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.url, link.rel))
+		}
+	}
+	return strings.Join(links, ", ")
+}
+
+// SetHeader set Link header to http response
+func (l Links) SetHeader(w http.ResponseWriter) {
+	if header := l.Header(); header != "" {
+		w.Header().Set("Link", header)
+	}
+}
+
+// EncodeCursor encode cursor value (ex: last id and sort value of current page) to opaque cursor string
+func EncodeCursor(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decode opaque cursor string to target (must in pointer)
+func DecodeCursor(cursor string, target interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(b, target); err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	return nil
+}
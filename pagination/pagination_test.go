@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Bhinneka/golib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Testcase #1: Positive, default value", func(t *testing.T) {
+		p, err := Parse(url.Values{})
+		assert.NoError(t, err)
+		assert.Equal(t, &Pagination{Page: 1, Limit: DefaultLimit}, p)
+		assert.Equal(t, 0, p.Offset())
+	})
+	t.Run("Testcase #2: Positive, clamp limit", func(t *testing.T) {
+		p, err := Parse(url.Values{"page": {"3"}, "limit": {"1000"}, "sort": {"-createdAt,name"}})
+		assert.NoError(t, err)
+		assert.Equal(t, MaxLimit, p.Limit)
+		assert.Equal(t, 200, p.Offset())
+		assert.Equal(t, []SortField{{Field: "createdAt", Desc: true}, {Field: "name"}}, p.SortFields())
+	})
+	t.Run("Testcase #3: Negative, invalid page", func(t *testing.T) {
+		_, err := Parse(url.Values{"page": {"one"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestMetaAndLinks(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/order?page=2&limit=10&status=active", nil)
+	p := &Pagination{Page: 2, Limit: 10}
+
+	assert.Equal(t, golib.Meta{Page: 2, Limit: 10, TotalRecords: 35, TotalPages: 4}, p.Meta(35))
+	assert.Equal(t, 0, TotalPages(0, 10))
+
+	links := p.Links(req, 35)
+	assert.Equal(t, "http://example.com/api/order?page=2&limit=10&status=active", links.Self)
+	assert.Equal(t, "http://example.com/api/order?limit=10&page=1&status=active", links.First)
+	assert.Equal(t, "http://example.com/api/order?limit=10&page=1&status=active", links.Prev)
+	assert.Equal(t, "http://example.com/api/order?limit=10&page=3&status=active", links.Next)
+	assert.Equal(t, "http://example.com/api/order?limit=10&page=4&status=active", links.Last)
+
+	w := httptest.NewRecorder()
+	links.SetHeader(w)
+	assert.Equal(t, `<http://example.com/api/order?limit=10&page=1&status=active>; rel="first", `+
+		`<http://example.com/api/order?limit=10&page=1&status=active>; rel="prev", `+
+		`<http://example.com/api/order?limit=10&page=3&status=active>; rel="next", `+
+		`<http://example.com/api/order?limit=10&page=4&status=active>; rel="last"`, w.Header().Get("Link"))
+
+	last := (&Pagination{Page: 4, Limit: 10}).Links(req, 35)
+	assert.Equal(t, "", last.Next)
+
+	empty := (&Pagination{Page: 1, Limit: 10}).Links(req, 0)
+	assert.Equal(t, "", empty.Prev)
+	assert.Equal(t, empty.First, empty.Last)
+}
+
+func TestCursor(t *testing.T) {
+	type cursor struct {
+		ID        int    `json:"id"`
+		CreatedAt string `json:"createdAt"`
+	}
+
+	next, err := EncodeCursor(cursor{ID: 10, CreatedAt: "2019-08-28"})
+	assert.NoError(t, err)
+
+	var decoded cursor
+	assert.NoError(t, DecodeCursor(next, &decoded))
+	assert.Equal(t, cursor{ID: 10, CreatedAt: "2019-08-28"}, decoded)
+	assert.Error(t, DecodeCursor("!invalid", &decoded))
+	assert.Error(t, DecodeCursor("aW52YWxpZA", &decoded))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/order?page=2&limit=10", nil)
+	links := CursorLinks(req, next, "")
+	assert.Equal(t, "http://example.com/api/order?cursor="+next+"&limit=10", links.Next)
+	assert.Equal(t, "", links.Prev)
+
+	p, err := Parse(url.Values{"cursor": {next}})
+	assert.NoError(t, err)
+	assert.Equal(t, CursorMeta{Limit: DefaultLimit, NextCursor: next}, p.CursorMeta(next, ""))
+}
@@ -1,14 +1,52 @@
 package golib
 
 import (
+	"encoding"
 	"fmt"
 	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// ParseToFormValue convert struct to form values
-func ParseToFormValue(source interface{}) (form url.Values, err error) {
+// FormNotation notation of nested struct, map and slice key in ParseToFormValue
+type FormNotation int
+
+const (
+	// FormNotationDot dotted key for nested struct and map (ex: address.city=value)
+	// and repeated key for slice (ex: tags=a&tags=b)
+	FormNotationDot FormNotation = iota
+	// FormNotationBracket bracket key for nested struct and map (ex: address[city]=value)
+	// and key with [] suffix for slice (ex: tags[]=a&tags[]=b)
+	FormNotationBracket
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// formEncoder encode value to form with key
+type formEncoder func(form url.Values, key string, v reflect.Value, notation FormNotation)
+
+// formValueField cached encode plan of struct field for ParseToFormValue
+type formValueField struct {
+	index     int
+	key       string
+	embedded  bool
+	omitEmpty bool
+	encode    formEncoder
+}
+
+// formValuePlans cached list of formValueField by struct type
+var formValuePlans sync.Map
+
+// ParseToFormValue convert struct to form values with key in json tag (or field name if json tag is empty).
+// Field with "-" json tag and empty field with omitempty is skipped, nil pointer is skipped.
+// Nested struct, map and slice is encoded with notation (default FormNotationDot), slice of struct or map is encoded with index
+// (ex: items.0.name or items[0][name]), encoding.TextMarshaler is used when implemented by field type
+// and time.Time is formatted with layout in "format" tag (default RFC3339)
+func ParseToFormValue(source interface{}, notation ...FormNotation) (form url.Values, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%v", r)
@@ -27,25 +65,229 @@ func ParseToFormValue(source interface{}) (form url.Values, err error) {
 		return
 	}
 
-	objType := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
+	formNotation := FormNotationDot
+	if len(notation) > 0 {
+		formNotation = notation[0]
+	}
 
-		jsonTag := objType.Field(i).Tag.Get("json")
-		if jsonTags := strings.Split(jsonTag, ","); len(jsonTags) > 0 {
-			jsonTag = jsonTags[0]
+	encodeFormStruct(form, "", val, formNotation)
+	return
+}
+
+func getFormValuePlan(typ reflect.Type) []formValueField {
+	if plan, ok := formValuePlans.Load(typ); ok {
+		return plan.([]formValueField)
+	}
+
+	plan := make([]formValueField, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // unexported field
+			continue
 		}
-		if jsonTag == "" {
-			jsonTag = objType.Field(i).Name
+
+		jsonTags := strings.Split(field.Tag.Get("json"), ",")
+		key := jsonTags[0]
+		if key == "-" {
+			continue
 		}
 
-		var value string
-		if field.Kind() != reflect.String {
-			value = fmt.Sprint(field.Interface())
-		} else {
-			value = field.String()
+		f := formValueField{
+			index:     i,
+			key:       key,
+			omitEmpty: StringInSlice("omitempty", jsonTags[1:]),
+			encode:    compileFormEncoder(field.Type, field.Tag),
 		}
-		form.Set(jsonTag, value)
+		if key == "" {
+			f.key = field.Name
+			f.embedded = field.Anonymous && isFormContainer(field.Type) && indirectType(field.Type).Kind() == reflect.Struct
+		}
+		plan = append(plan, f)
 	}
-	return
+
+	formValuePlans.Store(typ, plan)
+	return plan
+}
+
+func encodeFormStruct(form url.Values, prefix string, val reflect.Value, notation FormNotation) {
+	for _, f := range getFormValuePlan(val.Type()) {
+		field := val.Field(f.index)
+		if f.omitEmpty && isEmptyValue(field) {
+			continue
+		}
+
+		if f.embedded { // embedded struct, field promoted to parent
+			f.encode(form, prefix, field, notation)
+			continue
+		}
+		f.encode(form, formKey(prefix, f.key, notation), field, notation)
+	}
+}
+
+func formKey(prefix, key string, notation FormNotation) string {
+	switch {
+	case prefix == "":
+		return key
+	case notation == FormNotationBracket:
+		return prefix + "[" + key + "]"
+	}
+	return prefix + "." + key
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// isFormContainer check type is encoded into multiple key (struct and map)
+func isFormContainer(typ reflect.Type) bool {
+	typ = indirectType(typ)
+	if typ == timeType || typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType) {
+		return false
+	}
+	return typ.Kind() == reflect.Struct || typ.Kind() == reflect.Map
+}
+
+func compileFormEncoder(typ reflect.Type, tag reflect.StructTag) formEncoder {
+	if typ == timeType {
+		layout := tag.Get("format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+			form.Add(key, v.Interface().(time.Time).Format(layout))
+		}
+	}
+
+	if typ.Kind() != reflect.Ptr && typ.Kind() != reflect.Interface {
+		if typ.Implements(textMarshalerType) {
+			return encodeFormTextMarshaler
+		}
+		if reflect.PtrTo(typ).Implements(textMarshalerType) {
+			fallback := compileFormKindEncoder(typ, tag)
+			return func(form url.Values, key string, v reflect.Value, notation FormNotation) {
+				if !v.CanAddr() {
+					fallback(form, key, v, notation)
+					return
+				}
+				encodeFormTextMarshaler(form, key, v.Addr(), notation)
+			}
+		}
+	}
+
+	return compileFormKindEncoder(typ, tag)
+}
+
+func compileFormKindEncoder(typ reflect.Type, tag reflect.StructTag) formEncoder {
+	switch typ.Kind() {
+	case reflect.String:
+		return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+			form.Add(key, v.String())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+			form.Add(key, strconv.FormatInt(v.Int(), 10))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+			form.Add(key, strconv.FormatUint(v.Uint(), 10))
+		}
+	case reflect.Float32, reflect.Float64:
+		bitSize := typ.Bits()
+		return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+			form.Add(key, strconv.FormatFloat(v.Float(), 'f', -1, bitSize))
+		}
+	case reflect.Bool:
+		return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+			form.Add(key, strconv.FormatBool(v.Bool()))
+		}
+
+	case reflect.Ptr:
+		elemEncoder := compileFormEncoder(typ.Elem(), tag)
+		return func(form url.Values, key string, v reflect.Value, notation FormNotation) {
+			if !v.IsNil() {
+				elemEncoder(form, key, v.Elem(), notation)
+			}
+		}
+	case reflect.Interface:
+		return func(form url.Values, key string, v reflect.Value, notation FormNotation) {
+			if !v.IsNil() {
+				compileFormEncoder(v.Elem().Type(), tag)(form, key, v.Elem(), notation)
+			}
+		}
+
+	case reflect.Struct:
+		return func(form url.Values, key string, v reflect.Value, notation FormNotation) {
+			encodeFormStruct(form, key, v, notation)
+		}
+
+	case reflect.Map:
+		keyEncoder := compileFormMapKeyEncoder(typ.Key())
+		elemEncoder := compileFormEncoder(typ.Elem(), tag)
+		return func(form url.Values, key string, v reflect.Value, notation FormNotation) {
+			mapKeys := v.MapKeys()
+			keys := make([]string, len(mapKeys))
+			values := make(map[string]reflect.Value, len(mapKeys))
+			for i, k := range mapKeys {
+				keys[i] = keyEncoder(k)
+				values[keys[i]] = v.MapIndex(k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				elemEncoder(form, formKey(key, k, notation), values[k], notation)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 { // []byte
+			return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+				form.Add(key, string(v.Bytes()))
+			}
+		}
+
+		isIndexed := isFormContainer(typ.Elem())
+		elemEncoder := compileFormEncoder(typ.Elem(), tag)
+		return func(form url.Values, key string, v reflect.Value, notation FormNotation) {
+			for i := 0; i < v.Len(); i++ {
+				elemKey := key
+				switch {
+				case isIndexed:
+					elemKey = formKey(key, strconv.Itoa(i), notation)
+				case notation == FormNotationBracket:
+					elemKey = key + "[]"
+				}
+				elemEncoder(form, elemKey, v.Index(i), notation)
+			}
+		}
+	}
+
+	return func(form url.Values, key string, v reflect.Value, _ FormNotation) {
+		form.Add(key, fmt.Sprint(v.Interface()))
+	}
+}
+
+func compileFormMapKeyEncoder(typ reflect.Type) func(k reflect.Value) string {
+	switch {
+	case typ.Kind() == reflect.String:
+		return func(k reflect.Value) string { return k.String() }
+	case typ.Implements(textMarshalerType):
+		return func(k reflect.Value) string {
+			b, _ := k.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(b)
+		}
+	}
+	return func(k reflect.Value) string { return fmt.Sprint(k.Interface()) }
+}
+
+func encodeFormTextMarshaler(form url.Values, key string, v reflect.Value, _ FormNotation) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return
+	}
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		panic(fmt.Errorf("cannot marshal field %s: %v", key, err))
+	}
+	form.Add(key, string(b))
 }
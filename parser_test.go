@@ -1,7 +1,12 @@
 package golib
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParseToFormValue(t *testing.T) {
@@ -59,3 +64,117 @@ func TestParseToFormValue(t *testing.T) {
 		})
 	}
 }
+
+type formCode string
+
+func (c formCode) MarshalText() ([]byte, error) {
+	if c == "" {
+		return nil, errors.New("empty code")
+	}
+	return []byte(strings.ToUpper(string(c))), nil
+}
+
+func TestParseToFormValueEncoding(t *testing.T) {
+	type Item struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty"`
+	}
+	type Address struct {
+		City    string `json:"city"`
+		ZipCode string `json:"zipCode,omitempty"`
+	}
+	type Embed struct {
+		Page int `json:"page"`
+	}
+	type Source struct {
+		Embed
+		Name      string            `json:"name"`
+		Secret    string            `json:"-"`
+		Note      string            `json:"note,omitempty"`
+		Tags      []string          `json:"tags"`
+		Items     []Item            `json:"items"`
+		Address   Address           `json:"address"`
+		Billing   *Address          `json:"billing"`
+		Attrs     map[string]string `json:"attrs"`
+		Price     float64           `json:"price"`
+		Code      formCode          `json:"code"`
+		Date      time.Time         `json:"date" format:"2006-01-02"`
+		CreatedAt time.Time         `json:"createdAt"`
+		UpdatedAt time.Time         `json:"updatedAt,omitempty"`
+		private   string
+	}
+	source := Source{
+		Embed: Embed{Page: 1}, Name: "agungdp", Secret: "secret",
+		Tags:    []string{"a", "b"},
+		Items:   []Item{{SKU: "SKU1", Qty: 1}, {SKU: "SKU2", Qty: 2}},
+		Address: Address{City: "Jakarta"},
+		Attrs:   map[string]string{"color": "red", "size": "xl"},
+		Price:   1500000.5, Code: "abc",
+		Date:      time.Date(2019, 8, 28, 0, 0, 0, 0, time.UTC),
+		CreatedAt: time.Date(2019, 8, 28, 10, 0, 0, 0, time.UTC),
+		private:   "private",
+	}
+
+	t.Run("Testcase #1: Positive, dot notation", func(t *testing.T) {
+		form, err := ParseToFormValue(&source)
+		assert.NoError(t, err)
+		assert.Equal(t, "address.city=Jakarta&attrs.color=red&attrs.size=xl&code=ABC&createdAt=2019-08-28T10%3A00%3A00Z"+
+			"&date=2019-08-28&items.0.qty=1&items.0.sku=SKU1&items.1.qty=2&items.1.sku=SKU2&name=agungdp&page=1"+
+			"&price=1500000.5&tags=a&tags=b", form.Encode())
+	})
+	t.Run("Testcase #2: Positive, bracket notation", func(t *testing.T) {
+		source.Billing = &Address{City: "Bandung", ZipCode: "40111"}
+		defer func() { source.Billing = nil }()
+
+		form, err := ParseToFormValue(source, FormNotationBracket)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, form["tags[]"])
+		assert.Equal(t, "SKU2", form.Get("items[1][sku]"))
+		assert.Equal(t, "Jakarta", form.Get("address[city]"))
+		assert.Equal(t, "40111", form.Get("billing[zipCode]"))
+		assert.Equal(t, "xl", form.Get("attrs[size]"))
+		_, ok := form["address[zipCode]"]
+		assert.False(t, ok)
+	})
+	t.Run("Testcase #3: Negative, text marshaler error", func(t *testing.T) {
+		_, err := ParseToFormValue(Source{})
+		assert.Error(t, err)
+	})
+}
+
+func BenchmarkParseToFormValue(b *testing.B) {
+	type Source struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Gender   string
+		Number   int     `json:"number"`
+		Price    float64 `json:"price"`
+		IsActive bool    `json:"isActive"`
+	}
+	source := &Source{ID: "10", Name: "agungdp", Gender: "L", Number: 28, Price: 10.5, IsActive: true}
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseToFormValue(source); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// baseline of cached plan, plan is compiled on every iteration
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			formValuePlans.Range(func(key, _ interface{}) bool {
+				formValuePlans.Delete(key)
+				return true
+			})
+			b.StartTimer()
+
+			if _, err := ParseToFormValue(source); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package golib
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+)
+
+const (
+	// ProblemJSONContentType media type of problem details in JSON format (RFC 7807)
+	ProblemJSONContentType = "application/problem+json"
+	// ProblemXMLContentType media type of problem details in XML format (RFC 7807)
+	ProblemXMLContentType = "application/problem+xml"
+)
+
+type (
+	// ProblemDetails model of RFC 7807 problem details document with invalid-params extension
+	ProblemDetails struct {
+		XMLName       xml.Name       `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+		Type          string         `json:"type" xml:"type"`
+		Title         string         `json:"title" xml:"title"`
+		Status        int            `json:"status" xml:"status"`
+		Detail        string         `json:"detail,omitempty" xml:"detail,omitempty"`
+		Instance      string         `json:"instance,omitempty" xml:"instance,omitempty"`
+		InvalidParams []InvalidParam `json:"invalid-params,omitempty" xml:"invalid-params>i,omitempty"`
+	}
+
+	// InvalidParam model of invalid-params extension in problem details
+	InvalidParam struct {
+		Name    string `json:"name" xml:"name"`
+		Reason  string `json:"reason" xml:"reason"`
+		Code    string `json:"code,omitempty" xml:"code,omitempty"`
+		Pointer string `json:"pointer,omitempty" xml:"pointer,omitempty"`
+	}
+)
+
+// NewProblemDetails constructor, type is "about:blank" and title is http status text if empty
+func NewProblemDetails(status int, problemType, title, detail string) *ProblemDetails {
+	if problemType == "" {
+		problemType = "about:blank"
+	}
+	if title == "" {
+		title = http.StatusText(status)
+	}
+	return &ProblemDetails{Type: problemType, Title: title, Status: status, Detail: detail}
+}
+
+// ToProblemDetails convert multierror to problem details with every error item in invalid-params
+func (m *MultiError) ToProblemDetails(status int, detail string) *ProblemDetails {
+	problem := NewProblemDetails(status, "", "", detail)
+	for _, item := range m.Errors() {
+		problem.InvalidParams = append(problem.InvalidParams, InvalidParam{
+			Name: item.Key, Reason: item.Err.Error(), Code: item.Code, Pointer: item.Path,
+		})
+	}
+	return problem
+}
+
+// JSON for set http problem details response (Content-Type: application/problem+json)
+func (p *ProblemDetails) JSON(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", ProblemJSONContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// XML for set http problem details response (Content-Type: application/problem+xml)
+func (p *ProblemDetails) XML(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", ProblemXMLContentType)
+	w.WriteHeader(p.Status)
+	return xml.NewEncoder(w).Encode(p)
+}
+
+// Write for set http problem details response in JSON or XML negotiated from Accept header of request (default JSON)
+func (p *ProblemDetails) Write(w http.ResponseWriter, req *http.Request) error {
+	w.Header().Add("Vary", "Accept")
+	switch NegotiateContentType(req.Header.Get("Accept"), []string{ProblemJSONContentType, JSONContentType, ProblemXMLContentType, XMLContentType}) {
+	case ProblemXMLContentType, XMLContentType:
+		return p.XML(w)
+	}
+	return p.JSON(w)
+}
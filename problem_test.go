@@ -0,0 +1,50 @@
+package golib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemDetails(t *testing.T) {
+	multiError := NewMultiError()
+	multiError.AppendCode("address.city", ErrorCodeRequired, ErrRequired)
+	multiError.Append("page", errors.New("invalid page"))
+
+	problem := multiError.ToProblemDetails(http.StatusBadRequest, "request is invalid")
+
+	t.Run("Testcase #1: Positive, JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.NoError(t, problem.JSON(w))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, ProblemJSONContentType, w.Header().Get("Content-Type"))
+
+		var doc map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.Equal(t, "about:blank", doc["type"])
+		assert.Equal(t, "Bad Request", doc["title"])
+		assert.Equal(t, float64(400), doc["status"])
+		assert.Equal(t, "request is invalid", doc["detail"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"name": "address.city", "reason": "is required", "code": "required", "pointer": "/address/city"},
+			map[string]interface{}{"name": "page", "reason": "invalid page", "pointer": "/page"},
+		}, doc["invalid-params"])
+	})
+	t.Run("Testcase #2: Positive, XML", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.NoError(t, problem.XML(w))
+		assert.Equal(t, ProblemXMLContentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type>`)
+		assert.Contains(t, w.Body.String(), `<invalid-params><i><name>address.city</name>`)
+	})
+	t.Run("Testcase #3: Positive, custom type and title", func(t *testing.T) {
+		p := NewProblemDetails(http.StatusNotFound, "https://example.com/probs/not-found", "Order not found", "")
+		var resp HTTPResponse = p
+		assert.NoError(t, resp.JSON(httptest.NewRecorder()))
+		assert.Equal(t, "Order not found", p.Title)
+	})
+}
@@ -0,0 +1,119 @@
+package golib
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ResultLoggerFactory create result logger from backend URL
+type ResultLoggerFactory func(u *url.URL) (ResultLogger, error)
+
+// resultLoggerCopier result logger which can return copy of itself sharing the same backend connection,
+// GetResultLogger return a copy of cached logger so LastError isn't shared between callers
+type resultLoggerCopier interface {
+	copyResultLogger() ResultLogger
+}
+
+var (
+	resultLoggerFactoriesMu sync.RWMutex
+	resultLoggerFactories   = make(map[string]ResultLoggerFactory)
+
+	resultLoggersMu sync.Mutex
+	resultLoggers   = make(map[string]ResultLogger)
+)
+
+func init() {
+	RegisterResultLogger("file", newFileResultLoggerFromURL)
+	RegisterResultLogger("s3", newS3ResultLoggerFromURL)
+	RegisterResultLogger("redis", newRedisResultLoggerFromURL)
+	RegisterResultLogger("postgres", newSQLResultLoggerFromURL)
+	RegisterResultLogger("postgresql", newSQLResultLoggerFromURL)
+}
+
+// RegisterResultLogger register factory of result logger backend for URL scheme, existing factory is replaced
+func RegisterResultLogger(scheme string, factory ResultLoggerFactory) {
+	resultLoggerFactoriesMu.Lock()
+	resultLoggerFactories[strings.ToLower(scheme)] = factory
+	resultLoggerFactoriesMu.Unlock()
+
+	// logger of replaced factory is created again by GetResultLogger
+	resultLoggersMu.Lock()
+	resultLoggers = make(map[string]ResultLogger)
+	resultLoggersMu.Unlock()
+}
+
+// NewResultLogger create result logger of backend URL, path without scheme is file backend
+//
+//	file:///var/log/app
+//	s3://access:secret@bucket/prefix?endpoint=http://minio:9000&region=us-east-1
+//	redis://MASTER/stream?maxlen=10000
+//	postgres:///result_logs
+//	postgresql://user:pass@db:5432/app?table=result_logs&sslmode=disable
+func NewResultLogger(rawurl string) (ResultLogger, error) {
+	if !strings.Contains(rawurl, "://") {
+		return newFileResultLogger(rawurl), nil
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	resultLoggerFactoriesMu.RLock()
+	factory, ok := resultLoggerFactories[strings.ToLower(u.Scheme)]
+	resultLoggerFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported result logger '%s'", u.Scheme)
+	}
+	return factory(u)
+}
+
+// GetResultLogger function for getting result logger of LOG_DIR (see NewResultLogger),
+// file backend in <STORAGE_DIR>/logs/ is used if LOG_DIR is empty.
+// Backend is created once per LOG_DIR, LastError of returned logger is set if LOG_DIR is invalid
+func GetResultLogger() ResultLogger {
+	base := os.Getenv("LOG_DIR")
+	if base == "" {
+		base = storageDir + "/logs/"
+	}
+
+	resultLoggersMu.Lock()
+	logger, ok := resultLoggers[base]
+	resultLoggersMu.Unlock()
+
+	// backend is created without holding the lock so other callers aren't blocked while it connects,
+	// the first stored backend wins when it is created concurrently
+	if !ok {
+		created, err := NewResultLogger(base)
+		if err != nil {
+			return &FileResultLogger{lastError: err}
+		}
+		if created.LastError() != nil {
+			return created
+		}
+
+		resultLoggersMu.Lock()
+		if logger, ok = resultLoggers[base]; !ok {
+			logger = created
+			resultLoggers[base] = logger
+		}
+		resultLoggersMu.Unlock()
+	}
+
+	if copier, ok := logger.(resultLoggerCopier); ok {
+		return copier.copyResultLogger()
+	}
+	return logger
+}
+
+// newFileResultLoggerFromURL create file result logger from file:///<dir>, host must be empty or localhost,
+// relative directory is given as path without scheme
+func newFileResultLoggerFromURL(u *url.URL) (ResultLogger, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("host '%s' of file result logger is not supported, use file:///<dir> or path without scheme", u.Host)
+	}
+	return newFileResultLogger(u.Path), nil
+}
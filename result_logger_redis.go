@@ -0,0 +1,92 @@
+package golib
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+const redisResultLoggerStream = "result_logs"
+
+// RedisResultLogger result logger which append data as entry of Redis stream with "code" and "data" fields
+type RedisResultLogger struct {
+	Client *redis.Client
+	Stream string
+	// MaxLen approximate maximum length of stream, 0 is unlimited
+	MaxLen int64
+
+	lastError error
+}
+
+// newRedisResultLoggerFromURL create Redis result logger from redis://<node>/<stream>?maxlen=,
+// client is RedisClient(node) which is configured by REDIS_<node>_* environment
+func newRedisResultLoggerFromURL(u *url.URL) (ResultLogger, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("node of redis result logger is empty")
+	}
+
+	r := &RedisResultLogger{
+		Client: RedisClient(u.Host),
+		Stream: strings.Trim(u.Path, "/"),
+	}
+	if r.Stream == "" {
+		r.Stream = redisResultLoggerStream
+	}
+	if maxLen := u.Query().Get("maxlen"); maxLen != "" {
+		n, err := strconv.ParseInt(maxLen, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse '%s' to type int64", maxLen)
+		}
+		r.MaxLen = n
+	}
+	return r, nil
+}
+
+// LastError function for getting last error
+func (r *RedisResultLogger) LastError() error {
+	return r.lastError
+}
+
+// copyResultLogger copy of RedisResultLogger without last error
+func (r *RedisResultLogger) copyResultLogger() ResultLogger {
+	c := *r
+	c.lastError = nil
+	return &c
+}
+
+// Store append d to stream and return entry id
+func (r *RedisResultLogger) Store(c string, d []byte) string {
+	id, err := r.Client.XAdd(&redis.XAddArgs{
+		Stream:       r.Stream,
+		MaxLenApprox: r.MaxLen,
+		ID:           "*",
+		Values:       map[string]interface{}{"code": c, "data": d},
+	}).Result()
+	r.lastError = err
+	return id
+}
+
+// Get get data of stream entry with id p
+func (r *RedisResultLogger) Get(p string) string {
+	messages, err := r.Client.XRange(r.Stream, p, p).Result()
+	if err != nil {
+		r.lastError = err
+		return ""
+	}
+	if len(messages) == 0 {
+		r.lastError = redis.Nil
+		return ""
+	}
+
+	r.lastError = nil
+	data, _ := messages[0].Values["data"].(string)
+	return data
+}
+
+// RequestResponse append dt to stream and return entry id
+func (r *RedisResultLogger) RequestResponse(c string, dt string) string {
+	return r.Store(c, []byte(dt))
+}
@@ -0,0 +1,133 @@
+package golib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// redisStreamStub minimal Redis server which support XADD and XRANGE of single entry
+type redisStreamStub struct {
+	listener net.Listener
+	mu       sync.Mutex
+	seq      int
+	entries  map[string][]string
+}
+
+func newRedisStreamStub(t *testing.T) *redisStreamStub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := &redisStreamStub{listener: ln, entries: make(map[string][]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *redisStreamStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRESPArray(r)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		switch strings.ToLower(args[0]) {
+		case "xadd":
+			// XADD stream [MAXLEN ~ n] * field value ...
+			i := 2
+			if strings.ToLower(args[i]) == "maxlen" {
+				i += 3
+			}
+			s.seq++
+			id := fmt.Sprintf("%d-0", s.seq)
+			s.entries[args[1]+"/"+id] = args[i+1:]
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(id), id)
+		case "xrange":
+			fields, ok := s.entries[args[1]+"/"+args[2]]
+			if !ok {
+				io.WriteString(conn, "*0\r\n")
+				break
+			}
+			fmt.Fprintf(conn, "*1\r\n*2\r\n$%d\r\n%s\r\n*%d\r\n", len(args[2]), args[2], len(fields))
+			for _, f := range fields {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(f), f)
+			}
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func readRESPArray(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisResultLogger(t *testing.T) {
+	stub := newRedisStreamStub(t)
+	defer stub.listener.Close()
+
+	redisClient = make(map[string]*redis.Client)
+	redisClient["RESULT"] = redis.NewClient(&redis.Options{Addr: stub.listener.Addr().String()})
+	defer CloseRedis()
+
+	t.Run("Testcase #1: Positive, store and get", func(t *testing.T) {
+		logger, err := NewResultLogger("redis://RESULT/order_logs?maxlen=1000")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1000), logger.(*RedisResultLogger).MaxLen)
+
+		id := logger.Store("order", []byte(`{"id":1}`))
+		assert.NoError(t, logger.LastError())
+		assert.Equal(t, "1-0", id)
+
+		assert.Equal(t, `{"id":1}`, logger.Get(id))
+		assert.NoError(t, logger.LastError())
+	})
+
+	t.Run("Testcase #2: Negative, entry not found", func(t *testing.T) {
+		logger, _ := NewResultLogger("redis://RESULT")
+		assert.Equal(t, "", logger.Get("99-0"))
+		assert.Equal(t, redis.Nil, logger.LastError())
+	})
+
+	t.Run("Testcase #3: Negative, invalid maxlen", func(t *testing.T) {
+		_, err := NewResultLogger("redis://RESULT/order_logs?maxlen=ten")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,224 @@
+package golib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3TimeFormat    = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+	s3SignAlgorithm = "AWS4-HMAC-SHA256"
+)
+
+// S3ResultLogger result logger which store data as object in S3 compatible object storage (AWS S3, MinIO, ...)
+// with path style request signed by AWS signature version 4
+type S3ResultLogger struct {
+	// Endpoint base URL of object storage, ex: https://s3.ap-southeast-1.amazonaws.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+
+	lastError error
+}
+
+// newS3ResultLoggerFromURL create S3 result logger from s3://[access:secret@]bucket/prefix?endpoint=&region=,
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are used if credential is not in URL
+func newS3ResultLoggerFromURL(u *url.URL) (ResultLogger, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("bucket of s3 result logger is empty")
+	}
+
+	q := u.Query()
+	s := &S3ResultLogger{
+		Endpoint:  q.Get("endpoint"),
+		Region:    q.Get("region"),
+		Bucket:    u.Host,
+		Prefix:    strings.Trim(u.Path, "/"),
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		Client:    http.DefaultClient,
+	}
+	if u.User != nil {
+		s.AccessKey = u.User.Username()
+		s.SecretKey, _ = u.User.Password()
+	}
+	if s.Region == "" {
+		s.Region = s3DefaultRegion
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	return s, nil
+}
+
+// LastError function for getting last error
+func (s *S3ResultLogger) LastError() error {
+	return s.lastError
+}
+
+// copyResultLogger copy of S3ResultLogger without last error
+func (s *S3ResultLogger) copyResultLogger() ResultLogger {
+	c := *s
+	c.lastError = nil
+	return &c
+}
+
+// Store put d as object <prefix>/<c>/<time>_<unix>.<c> and return its key, empty if put failed
+func (s *S3ResultLogger) Store(c string, d []byte) string {
+	t := time.Now()
+	key := fmt.Sprintf("%s/%s_%d.%s", c, t.Format("150405"), t.UnixNano(), c)
+	if s.Prefix != "" {
+		key = s.Prefix + "/" + key
+	}
+
+	if _, s.lastError = s.do(http.MethodPut, key, d); s.lastError != nil {
+		return ""
+	}
+	return key
+}
+
+// Get get object with key p
+func (s *S3ResultLogger) Get(p string) string {
+	data, err := s.do(http.MethodGet, p, nil)
+	s.lastError = err
+	return string(data)
+}
+
+// RequestResponse store dt as object under <prefix>/<c>/ and return its key
+func (s *S3ResultLogger) RequestResponse(c string, dt string) string {
+	return s.Store(c, []byte(dt))
+}
+
+func (s *S3ResultLogger) do(method, key string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(s.Endpoint, "/")+"/"+s.Bucket+"/"+strings.TrimLeft(key, "/"), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	signS3Request(req, body, s.AccessKey, s.SecretKey, s.Region, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("s3 %s %s: %s", method, key, resp.Status)
+	}
+	return data, nil
+}
+
+// signS3Request set x-amz-date, x-amz-content-sha256 and Authorization header of AWS signature version 4,
+// all headers of request are signed
+func signS3Request(req *http.Request, body []byte, accessKey, secretKey, region string, t time.Time) {
+	t = t.UTC()
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", t.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if name := strings.ToLower(k); name != "authorization" {
+			headers[name] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, true),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := t.Format(s3DateFormat) + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3SignAlgorithm + "\n" + t.Format(s3TimeFormat) + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), t.Format(s3DateFormat))
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgorithm, accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape URI encode every byte except unreserved character and '/' if keepSlash is true
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && keepSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
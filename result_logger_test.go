@@ -0,0 +1,127 @@
+package golib
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryResultLogger in memory result logger for testing registry
+type memoryResultLogger struct {
+	data map[string]string
+}
+
+func (m *memoryResultLogger) Store(c string, d []byte) string {
+	m.data[c] = string(d)
+	return c
+}
+func (m *memoryResultLogger) Get(p string) string { return m.data[p] }
+func (m *memoryResultLogger) RequestResponse(c string, dt string) string {
+	return m.Store(c, []byte(dt))
+}
+func (m *memoryResultLogger) LastError() error { return nil }
+
+func TestNewResultLogger(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "golib-result")
+	defer os.RemoveAll(dir)
+
+	t.Run("Testcase #1: Positive, file URL store and get", func(t *testing.T) {
+		logger, err := NewResultLogger("file://" + dir)
+		assert.NoError(t, err)
+		assert.IsType(t, &FileResultLogger{}, logger)
+
+		name := logger.Store("order", []byte(`{"id":1}`))
+		assert.NoError(t, logger.LastError())
+		assert.Equal(t, `{"id":1}`, logger.Get(name))
+		assert.NoError(t, logger.LastError())
+	})
+
+	t.Run("Testcase #2: Positive, path without scheme", func(t *testing.T) {
+		logger, err := NewResultLogger(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, dir, logger.(*FileResultLogger).baseDir)
+	})
+
+	t.Run("Testcase #3: Positive, registered backend", func(t *testing.T) {
+		RegisterResultLogger("memory", func(u *url.URL) (ResultLogger, error) {
+			return &memoryResultLogger{data: make(map[string]string)}, nil
+		})
+
+		logger, err := NewResultLogger("memory://")
+		assert.NoError(t, err)
+		assert.Equal(t, "test", logger.Get(logger.Store("order", []byte("test"))))
+	})
+
+	t.Run("Testcase #4: Negative, unsupported scheme", func(t *testing.T) {
+		_, err := NewResultLogger("ftp://host/logs")
+		assert.Error(t, err)
+	})
+
+	t.Run("Testcase #5: Negative, file URL with host", func(t *testing.T) {
+		_, err := NewResultLogger("file://relative/dir")
+		assert.Error(t, err)
+	})
+}
+
+func TestGetResultLoggerURL(t *testing.T) {
+	defer os.Setenv("LOG_DIR", os.Getenv("LOG_DIR"))
+
+	t.Run("Testcase #1: Positive, LOG_DIR URL", func(t *testing.T) {
+		os.Setenv("LOG_DIR", "memory://")
+		RegisterResultLogger("memory", func(u *url.URL) (ResultLogger, error) {
+			return &memoryResultLogger{data: make(map[string]string)}, nil
+		})
+		assert.IsType(t, &memoryResultLogger{}, GetResultLogger())
+	})
+
+	t.Run("Testcase #2: Positive, backend is created once per LOG_DIR", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "golib-result")
+		defer os.RemoveAll(dir)
+
+		var created int
+		RegisterResultLogger("memory", func(u *url.URL) (ResultLogger, error) {
+			created++
+			return &memoryResultLogger{data: make(map[string]string)}, nil
+		})
+		os.Setenv("LOG_DIR", "memory://")
+		assert.True(t, GetResultLogger() == GetResultLogger())
+		assert.Equal(t, 1, created)
+
+		os.Setenv("LOG_DIR", "file://"+dir)
+		l1, l2 := GetResultLogger(), GetResultLogger()
+		l1.Get("missing")
+		assert.Error(t, l1.LastError())
+		assert.NoError(t, l2.LastError())
+		assert.Equal(t, dir, l2.(*FileResultLogger).baseDir)
+	})
+
+	t.Run("Testcase #3: Positive, factory isn't called with cache locked", func(t *testing.T) {
+		RegisterResultLogger("lazy", func(u *url.URL) (ResultLogger, error) {
+			RegisterResultLogger("memory", func(u *url.URL) (ResultLogger, error) {
+				return &memoryResultLogger{data: make(map[string]string)}, nil
+			})
+			return NewResultLogger("memory://")
+		})
+		os.Setenv("LOG_DIR", "lazy://")
+
+		done := make(chan ResultLogger)
+		go func() { done <- GetResultLogger() }()
+		select {
+		case logger := <-done:
+			assert.IsType(t, &memoryResultLogger{}, logger)
+		case <-time.After(time.Second):
+			t.Fatal("GetResultLogger is deadlocked")
+		}
+	})
+
+	t.Run("Testcase #4: Negative, invalid LOG_DIR", func(t *testing.T) {
+		os.Setenv("LOG_DIR", "ftp://host/logs")
+		logger := GetResultLogger()
+		assert.Error(t, logger.LastError())
+		assert.Equal(t, "", StoreRequestResponse("200", []byte("test"), []byte("test")))
+	})
+}
@@ -0,0 +1,363 @@
+package golib
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const rotatingFileTimeFormat = "20060102T150405.000"
+
+var (
+	// RotateMaxSize default maximum size in bytes of rotating file before it is rotated, 0 disable size based rotation
+	RotateMaxSize int64 = 100 << 20
+	// RotateMaxAge default maximum age of rotating file before it is rotated, 0 disable age based rotation
+	RotateMaxAge = 24 * time.Hour
+	// RotateMaxBackups default number of rotated files to keep, 0 keep all
+	RotateMaxBackups = 30
+	// RotateCompress default compression of rotated files with gzip
+	RotateCompress = true
+
+	rotatingFilesMu sync.Mutex
+	rotatingFiles   = make(map[string]*RotatingFile)
+	sighupOnce      sync.Once
+)
+
+// RotatingFile io.WriteCloser which append to file and rotate it when it is larger than MaxSize or older than MaxAge.
+// Rotated file is renamed to <Filename>.<timestamp>, compressed with gzip if Compress is true
+// and only newest MaxBackups rotated files are kept. It is safe for concurrent use
+type RotatingFile struct {
+	// Filename path of file, directory must exist
+	Filename string
+	// MaxSize maximum size in bytes, 0 disable size based rotation
+	MaxSize int64
+	// MaxAge maximum age since file is created (modification time for existing file), 0 disable age based rotation
+	MaxAge time.Duration
+	// MaxBackups number of rotated files to keep, 0 keep all
+	MaxBackups int
+	// Compress compress rotated file with gzip
+	Compress bool
+
+	// refs number of OpenRotatingFile holder, guarded by rotatingFilesMu
+	refs int
+
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	startedAt time.Time
+	closed    bool
+
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+}
+
+// NewRotatingFile create rotating file with RotateMaxSize, RotateMaxAge, RotateMaxBackups and RotateCompress,
+// file is opened on first write
+func NewRotatingFile(filename string) *RotatingFile {
+	return &RotatingFile{
+		Filename:   filename,
+		MaxSize:    RotateMaxSize,
+		MaxAge:     RotateMaxAge,
+		MaxBackups: RotateMaxBackups,
+		Compress:   RotateCompress,
+	}
+}
+
+// OpenRotatingFile return opened rotating file of filename, same handle is returned for the same file
+// and it is reopened when process receive SIGHUP (ex: after logrotate move the file).
+// Handle is shared, file is closed only when every holder has called Close
+func OpenRotatingFile(filename string) (*RotatingFile, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rotatingFilesMu.Lock()
+	defer rotatingFilesMu.Unlock()
+
+	if f, ok := rotatingFiles[path]; ok {
+		f.refs++
+		return f, nil
+	}
+
+	f := NewRotatingFile(path)
+	if err := f.Reopen(); err != nil {
+		return nil, err
+	}
+	f.refs = 1
+	rotatingFiles[path] = f
+	sighupOnce.Do(watchSIGHUP)
+	return f, nil
+}
+
+// ReopenRotatingFiles reopen all files opened with OpenRotatingFile
+func ReopenRotatingFiles() error {
+	rotatingFilesMu.Lock()
+	defer rotatingFilesMu.Unlock()
+
+	var lastErr error
+	for _, f := range rotatingFiles {
+		if err := f.Reopen(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func watchSIGHUP() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			ReopenRotatingFiles()
+		}
+	}()
+}
+
+// Write append p to file, file is rotated first if it will exceed MaxSize or older than MaxAge.
+// os.ErrClosed is returned after Close
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate rotate file immediately
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	return r.rotate()
+}
+
+// Reopen close and open file again, used when file is moved or removed by external tool
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.open()
+}
+
+// Close close file and wait until compression and cleanup of rotated files are finished.
+// Shared handle of OpenRotatingFile is only closed by its last holder
+func (r *RotatingFile) Close() error {
+	rotatingFilesMu.Lock()
+	if rotatingFiles[r.Filename] == r {
+		if r.refs--; r.refs > 0 {
+			rotatingFilesMu.Unlock()
+			return nil
+		}
+		delete(rotatingFiles, r.Filename)
+	}
+	rotatingFilesMu.Unlock()
+
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	r.millWg.Wait()
+	return err
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+	r.startedAt = time.Now()
+	if r.size > 0 {
+		r.startedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (r *RotatingFile) shouldRotate(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.MaxSize > 0 && r.size+n > r.MaxSize {
+		return true
+	}
+	return r.MaxAge > 0 && time.Since(r.startedAt) >= r.MaxAge
+}
+
+// rotate rename current file to backup name and open new file, backup is compressed and cleaned up in background
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	backup := r.backupName(time.Now())
+	if err := os.Rename(r.Filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.millWg.Add(1)
+	go func() {
+		defer r.millWg.Done()
+		r.mill(backup)
+	}()
+	return nil
+}
+
+// mill compress rotated file and remove old rotated files exceeding MaxBackups
+func (r *RotatingFile) mill(backup string) {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	if r.Compress {
+		if err := gzipFile(backup); err != nil {
+			Log(ErrorLevel, err.Error(), "rotating_file", "compress")
+		}
+	}
+
+	if r.MaxBackups <= 0 {
+		return
+	}
+	backups := r.backups()
+	if len(backups) <= r.MaxBackups {
+		return
+	}
+	for _, name := range backups[:len(backups)-r.MaxBackups] {
+		os.Remove(name)
+	}
+}
+
+// backupName unused name <Filename>.<timestamp>[-<seq>] of backup rotated at t,
+// sequence is added when file is rotated more than once in the same millisecond
+func (r *RotatingFile) backupName(t time.Time) string {
+	base := r.Filename + "." + t.Format(rotatingFileTimeFormat)
+	name := base
+	for seq := 1; fileExists(name) || fileExists(name+".gz"); seq++ {
+		name = base + "-" + strconv.Itoa(seq)
+	}
+	return name
+}
+
+// backups rotated files of Filename sorted from the oldest
+func (r *RotatingFile) backups() []string {
+	matches, _ := filepath.Glob(r.Filename + ".*")
+
+	type backup struct {
+		name string
+		t    time.Time
+		seq  int
+	}
+	var backups []backup
+	for _, name := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, r.Filename+"."), ".gz")
+		seq := 0
+		if i := strings.LastIndex(ts, "-"); i >= 0 {
+			n, err := strconv.Atoi(ts[i+1:])
+			if err != nil {
+				continue
+			}
+			ts, seq = ts[:i], n
+		}
+		if t, err := time.Parse(rotatingFileTimeFormat, ts); err == nil {
+			backups = append(backups, backup{name: name, t: t, seq: seq})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.Before(backups[j].t)
+		}
+		return backups[i].seq < backups[j].seq
+	})
+
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = b.name
+	}
+	return names
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// gzipFile compress file to <name>.gz and remove it
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	return os.Remove(name)
+}
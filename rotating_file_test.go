@@ -0,0 +1,221 @@
+package golib
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempRotatingDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "golib-rotating")
+	assert.NoError(t, err)
+	return dir
+}
+
+func readGzipFile(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(zr)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Run("Testcase #1: Positive, rotate by size and compress backup", func(t *testing.T) {
+		dir := tempRotatingDir(t)
+		defer os.RemoveAll(dir)
+
+		f := NewRotatingFile(filepath.Join(dir, "app.log"))
+		f.MaxSize = 10
+		f.Compress = true
+
+		for _, s := range []string{"12345", "67890", "abcde"} {
+			n, err := f.Write([]byte(s))
+			assert.NoError(t, err)
+			assert.Equal(t, 5, n)
+		}
+		assert.NoError(t, f.Close())
+
+		b, _ := ioutil.ReadFile(f.Filename)
+		assert.Equal(t, "abcde", string(b))
+
+		backups := f.backups()
+		assert.Len(t, backups, 1)
+		assert.True(t, strings.HasSuffix(backups[0], ".gz"))
+		assert.Equal(t, "1234567890", readGzipFile(t, backups[0]))
+	})
+
+	t.Run("Testcase #2: Positive, rotate by age", func(t *testing.T) {
+		dir := tempRotatingDir(t)
+		defer os.RemoveAll(dir)
+
+		f := NewRotatingFile(filepath.Join(dir, "app.log"))
+		f.MaxAge = time.Hour
+		f.Compress = false
+
+		f.Write([]byte("old"))
+		f.startedAt = time.Now().Add(-2 * time.Hour)
+		f.Write([]byte("new"))
+		assert.NoError(t, f.Close())
+
+		backups := f.backups()
+		assert.Len(t, backups, 1)
+		b, _ := ioutil.ReadFile(backups[0])
+		assert.Equal(t, "old", string(b))
+		b, _ = ioutil.ReadFile(f.Filename)
+		assert.Equal(t, "new", string(b))
+	})
+
+	t.Run("Testcase #3: Positive, keep max backups", func(t *testing.T) {
+		dir := tempRotatingDir(t)
+		defer os.RemoveAll(dir)
+
+		f := NewRotatingFile(filepath.Join(dir, "app.log"))
+		f.MaxBackups = 2
+		f.Compress = false
+
+		for i := 0; i < 4; i++ {
+			f.Write([]byte(fmt.Sprint(i)))
+			assert.NoError(t, f.Rotate())
+		}
+		assert.NoError(t, f.Close())
+
+		backups := f.backups()
+		assert.Len(t, backups, 2)
+		b, _ := ioutil.ReadFile(backups[0])
+		assert.Equal(t, "2", string(b))
+		b, _ = ioutil.ReadFile(backups[1])
+		assert.Equal(t, "3", string(b))
+	})
+
+	t.Run("Testcase #4: Positive, concurrent write", func(t *testing.T) {
+		dir := tempRotatingDir(t)
+		defer os.RemoveAll(dir)
+
+		f := NewRotatingFile(filepath.Join(dir, "app.log"))
+		f.MaxSize = 1 << 20
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					f.Write([]byte("0123456789\n"))
+				}
+			}()
+		}
+		wg.Wait()
+		assert.NoError(t, f.Close())
+
+		b, _ := ioutil.ReadFile(f.Filename)
+		assert.Equal(t, strings.Repeat("0123456789\n", 1000), string(b))
+	})
+
+	t.Run("Testcase #5: Positive, rotate twice in the same millisecond", func(t *testing.T) {
+		dir := tempRotatingDir(t)
+		defer os.RemoveAll(dir)
+
+		f := NewRotatingFile(filepath.Join(dir, "app.log"))
+		now := time.Now()
+		first := f.backupName(now)
+		assert.NoError(t, ioutil.WriteFile(first+".gz", []byte("first"), 0664))
+		second := f.backupName(now)
+		assert.NoError(t, ioutil.WriteFile(second, []byte("second"), 0664))
+
+		assert.Equal(t, first+"-1", second)
+		assert.Equal(t, first+"-2", f.backupName(now))
+		assert.Equal(t, []string{first + ".gz", second}, f.backups())
+	})
+
+	t.Run("Testcase #6: Negative, directory doesn't exist", func(t *testing.T) {
+		f := NewRotatingFile("/nonexistent/app.log")
+		_, err := f.Write([]byte("test"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Testcase #7: Negative, write after close", func(t *testing.T) {
+		dir := tempRotatingDir(t)
+		defer os.RemoveAll(dir)
+
+		f := NewRotatingFile(filepath.Join(dir, "app.log"))
+		f.Write([]byte("test"))
+		assert.NoError(t, f.Close())
+		_, err := f.Write([]byte("test"))
+		assert.Equal(t, os.ErrClosed, err)
+		assert.Equal(t, os.ErrClosed, f.Rotate())
+	})
+}
+
+func TestOpenRotatingFile(t *testing.T) {
+	dir := tempRotatingDir(t)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	t.Run("Testcase #1: Positive, same handle for same file", func(t *testing.T) {
+		f1, err := OpenRotatingFile(name)
+		assert.NoError(t, err)
+		f2, err := OpenRotatingFile(name)
+		assert.NoError(t, err)
+		assert.True(t, f1 == f2)
+		assert.NoError(t, f1.Close())
+		assert.NoError(t, f2.Close())
+	})
+
+	t.Run("Testcase #2: Positive, shared handle is closed by its last holder", func(t *testing.T) {
+		f1, _ := OpenRotatingFile(name)
+		f2, _ := OpenRotatingFile(name)
+		assert.NoError(t, f1.Close())
+
+		f3, _ := OpenRotatingFile(name)
+		assert.True(t, f2 == f3)
+		_, err := f2.Write([]byte("shared"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, f2.Close())
+		assert.NoError(t, f3.Close())
+		f4, _ := OpenRotatingFile(name)
+		assert.False(t, f1 == f4)
+		assert.NoError(t, f4.Close())
+		assert.NoError(t, os.Remove(name))
+	})
+
+	t.Run("Testcase #3: Positive, reopen on SIGHUP", func(t *testing.T) {
+		f, _ := OpenRotatingFile(name)
+		f.Write([]byte("before"))
+
+		// logrotate move file then send SIGHUP
+		assert.NoError(t, os.Rename(name, name+".1"))
+		assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(name)
+			return err == nil
+		}, time.Second, 5*time.Millisecond)
+
+		f.Write([]byte("after"))
+		assert.NoError(t, f.Close())
+
+		b, _ := ioutil.ReadFile(name + ".1")
+		assert.Equal(t, "before", string(b))
+		b, _ = ioutil.ReadFile(name)
+		assert.Equal(t, "after", string(b))
+	})
+
+	t.Run("Testcase #4: Negative, directory doesn't exist", func(t *testing.T) {
+		_, err := OpenRotatingFile("/nonexistent/app.log")
+		assert.Error(t, err)
+	})
+}
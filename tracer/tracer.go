@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Bhinneka/golib"
 	opentracing "github.com/opentracing/opentracing-go"
 	ext "github.com/opentracing/opentracing-go/ext"
 )
@@ -23,6 +24,11 @@ type Tracer interface {
 	Finish(tags ...map[string]interface{})
 }
 
+func init() {
+	golib.TraceErrorFunc = SetError
+	golib.TraceIDFunc = GetTraceID
+}
+
 type opentracingTracer struct {
 	ctx  context.Context
 	span opentracing.Span
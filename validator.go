@@ -0,0 +1,297 @@
+package golib
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValidatorFunc validation rule function for validate tag, value is field value (pointer already dereferenced)
+// and param is rule parameter after "=" (ex: "100" in "max=100")
+type ValidatorFunc func(value interface{}, param string) error
+
+var (
+	validatorMu    sync.RWMutex
+	validatorRules = map[string]ValidatorFunc{
+		"min":      validateMin,
+		"max":      validateMax,
+		"oneof":    validateOneOf,
+		"email":    validateStringRule(ValidateEmail),
+		"url":      validateStringRule(ValidateURL),
+		"phone":    validateStringRule(ValidatePhoneNumber),
+		"alphanum": validateAlphanumeric,
+	}
+
+	// ErrRequired error when required field is empty
+	ErrRequired = errors.New("is required")
+)
+
+// RegisterValidator register custom named rule for validate tag, existing rule with same name will be replaced
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validatorMu.Lock()
+	defer validatorMu.Unlock()
+	validatorRules[name] = fn
+}
+
+func getValidator(name string) (ValidatorFunc, bool) {
+	validatorMu.RLock()
+	defer validatorMu.RUnlock()
+	fn, ok := validatorRules[name]
+	return fn, ok
+}
+
+// ValidateStruct validate struct fields with rules in validate tag (ex: `validate:"required,min=1,max=100,oneof=asc|desc"`),
+// target must struct or pointer of struct. Every failure is returned in *MultiError with json key of field
+func ValidateStruct(target interface{}) error {
+	refValue := reflect.ValueOf(target)
+	if refValue.Kind() == reflect.Ptr {
+		refValue = refValue.Elem()
+	}
+	if refValue.Kind() != reflect.Struct {
+		return fmt.Errorf("invalid target type %v: must struct", refValue.Kind())
+	}
+
+	errs := NewMultiError()
+	validateStruct("", refValue, errs)
+	if errs.HasError() {
+		return errs
+	}
+	return nil
+}
+
+// validateStructField cached validation plan of struct field for ValidateStruct
+type validateStructField struct {
+	index    int
+	key      string
+	embedded bool
+	nested   bool
+	required bool
+	rules    []validateRule
+}
+
+type validateRule struct {
+	name, param string
+}
+
+// validatePlans cached list of validateStructField by struct type
+var validatePlans sync.Map
+
+func getValidatePlan(typ reflect.Type) []validateStructField {
+	if plan, ok := validatePlans.Load(typ); ok {
+		return plan.([]validateStructField)
+	}
+
+	var plan []validateStructField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // unexported field
+			continue
+		}
+
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+
+		if field.Anonymous && key == "" {
+			if isBindStruct(field.Type) {
+				plan = append(plan, validateStructField{index: i, embedded: true})
+			}
+			continue
+		}
+
+		if key == "" {
+			key = field.Name
+		}
+
+		f := validateStructField{index: i, key: key, nested: isBindStruct(field.Type)}
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			rule = strings.TrimSpace(rule)
+			switch rule {
+			case "":
+				continue
+			case "required":
+				f.required = true
+				continue
+			}
+
+			var param string
+			if i := strings.Index(rule, "="); i >= 0 {
+				rule, param = rule[:i], rule[i+1:]
+			}
+			f.rules = append(f.rules, validateRule{name: rule, param: param})
+		}
+
+		if f.nested || f.required || len(f.rules) > 0 {
+			plan = append(plan, f)
+		}
+	}
+
+	validatePlans.Store(typ, plan)
+	return plan
+}
+
+func validateStruct(prefix string, refValue reflect.Value, errs *MultiError) {
+	for _, f := range getValidatePlan(refValue.Type()) {
+		field := refValue.Field(f.index)
+		if f.embedded {
+			if !(field.Kind() == reflect.Ptr && field.IsNil()) {
+				validateStruct(prefix, reflect.Indirect(field), errs)
+			}
+			continue
+		}
+
+		key := prefix + f.key
+		if f.required || len(f.rules) > 0 {
+			validateField(key, field, f.required, f.rules, errs)
+		}
+
+		if f.nested && !(field.Kind() == reflect.Ptr && field.IsNil()) {
+			validateStruct(key+".", reflect.Indirect(field), errs)
+		}
+	}
+}
+
+func validateField(key string, field reflect.Value, required bool, rules []validateRule, errs *MultiError) {
+	isEmpty := isEmptyValue(field)
+	if required && isEmpty {
+		errs.AppendCode(key, ErrorCodeRequired, ErrRequired)
+		return
+	}
+	if isEmpty { // only validate field with value
+		return
+	}
+
+	field = reflect.Indirect(field)
+	for _, rule := range rules {
+		fn, ok := getValidator(rule.name)
+		if !ok {
+			errs.Append(key, fmt.Errorf("unknown validation rule '%s'", rule.name))
+			continue
+		}
+
+		// min and max in slice is validating length, other rules validating each element
+		if field.Kind() == reflect.Slice && rule.name != "min" && rule.name != "max" {
+			for i := 0; i < field.Len(); i++ {
+				if err := fn(field.Index(i).Interface(), rule.param); err != nil {
+					errs.AppendItem(&ErrorItem{Key: key, Code: rule.name, Err: err, Params: map[string]interface{}{"param": rule.param}})
+					break
+				}
+			}
+			continue
+		}
+
+		if err := fn(field.Interface(), rule.param); err != nil {
+			errs.AppendItem(&ErrorItem{Key: key, Code: rule.name, Err: err, Params: map[string]interface{}{"param": rule.param}})
+		}
+	}
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// validateSize get size of value for min and max rule (length for string and slice, number for numeric)
+func validateSize(value interface{}, param string) (size, limit float64, isLength bool, err error) {
+	limit, err = strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid rule parameter '%s'", param)
+	}
+
+	refValue := reflect.ValueOf(value)
+	switch refValue.Kind() {
+	case reflect.String:
+		return float64(len(refValue.String())), limit, true, nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(refValue.Len()), limit, true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(refValue.Int()), limit, false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(refValue.Uint()), limit, false, nil
+	case reflect.Float32, reflect.Float64:
+		return refValue.Float(), limit, false, nil
+	}
+	return 0, 0, false, fmt.Errorf("cannot validate size of type %T", value)
+}
+
+func validateMin(value interface{}, param string) error {
+	size, limit, isLength, err := validateSize(value, param)
+	if err != nil {
+		return err
+	}
+	if size < limit {
+		if isLength {
+			return fmt.Errorf("length must be at least %s", param)
+		}
+		return fmt.Errorf("must be at least %s", param)
+	}
+	return nil
+}
+
+func validateMax(value interface{}, param string) error {
+	if str, ok := value.(string); ok {
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			return fmt.Errorf("invalid rule parameter '%s'", param)
+		}
+		if err := ValidateMaxInput(str, limit); err != nil {
+			return errors.New(strings.TrimSpace(err.Error()))
+		}
+		return nil
+	}
+
+	size, limit, isLength, err := validateSize(value, param)
+	if err != nil {
+		return err
+	}
+	if size > limit {
+		if isLength {
+			return fmt.Errorf("length must be at most %s", param)
+		}
+		return fmt.Errorf("must be at most %s", param)
+	}
+	return nil
+}
+
+func validateOneOf(value interface{}, param string) error {
+	options := strings.Split(param, "|")
+	if !StringInSlice(fmt.Sprint(value), options) {
+		return fmt.Errorf("must be one of [%s]", strings.Join(options, ", "))
+	}
+	return nil
+}
+
+func validateAlphanumeric(value interface{}, param string) error {
+	must, _ := strconv.ParseBool(param)
+	if !ValidateAlphanumeric(fmt.Sprint(value), must) {
+		return errors.New("must be alphanumeric")
+	}
+	return nil
+}
+
+func validateStringRule(fn func(string) error) ValidatorFunc {
+	return func(value interface{}, param string) error {
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot validate type %T", value)
+		}
+		return fn(str)
+	}
+}
@@ -0,0 +1,107 @@
+package golib
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStruct(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type Embed struct {
+		Page  int `json:"page" validate:"min=1"`
+		Limit int `json:"limit" validate:"min=1,max=100"`
+	}
+	type params struct {
+		Embed
+		Sort     string   `json:"sort" validate:"oneof=asc|desc"`
+		Name     string   `json:"name" validate:"required,max=5"`
+		Email    string   `json:"email" validate:"email"`
+		Website  *string  `json:"website" validate:"url"`
+		Phone    string   `json:"phone" validate:"phone"`
+		Code     string   `json:"code" validate:"alphanum"`
+		Tags     []string `json:"tags" validate:"max=2,alphanum"`
+		Address  Address  `json:"address"`
+		Optional *Address `json:"optional"`
+	}
+
+	t.Run("Testcase #1: Positive", func(t *testing.T) {
+		website := "https://www.bhinneka.com"
+		p := params{
+			Embed: Embed{Page: 1, Limit: 10}, Sort: "asc", Name: "agung", Email: "agung@bhinneka.com",
+			Website: &website, Phone: "0812345678", Code: "ABC123", Tags: []string{"a1", "b2"},
+			Address: Address{City: "Jakarta"},
+		}
+		assert.NoError(t, ValidateStruct(&p))
+	})
+	t.Run("Testcase #2: Positive, empty optional field is not validated", func(t *testing.T) {
+		p := params{Name: "agung", Address: Address{City: "Jakarta"}}
+		assert.NoError(t, ValidateStruct(p))
+	})
+	t.Run("Testcase #3: Negative, every failure reported by json key", func(t *testing.T) {
+		website := "https:///www.bhinneka.com"
+		p := params{
+			Embed: Embed{Page: -1, Limit: 101}, Sort: "random", Name: "agungdp", Email: "agung@",
+			Website: &website, Phone: "08a", Code: "ABC-123", Tags: []string{"a", "b", "c"},
+			Optional: &Address{},
+		}
+		err := ValidateStruct(&p)
+		multiError, ok := err.(*MultiError)
+		assert.True(t, ok)
+		errMap := multiError.ToMap()
+		assert.Equal(t, []string{"address.city", "code", "email", "limit", "name", "optional.city", "page", "phone", "sort", "tags", "website"}, sortedKeys(errMap))
+		assert.Equal(t, "is required", errMap["address.city"])
+		assert.Equal(t, "value is too long", errMap["name"])
+		assert.Equal(t, "must be one of [asc, desc]", errMap["sort"])
+		assert.Equal(t, "must be at most 100", errMap["limit"])
+		assert.Equal(t, "length must be at most 2", errMap["tags"])
+		assert.Equal(t, ErrBadFormatMail.Error(), errMap["email"])
+
+		codes := make(map[string]string)
+		for _, item := range multiError.Errors() {
+			codes[item.Key] = item.Code
+		}
+		assert.Equal(t, ErrorCodeRequired, codes["address.city"])
+		assert.Equal(t, "oneof", codes["sort"])
+		assert.True(t, errors.Is(err, ErrRequired))
+	})
+	t.Run("Testcase #4: Negative, invalid target and unknown rule", func(t *testing.T) {
+		assert.Error(t, ValidateStruct("test"))
+
+		type unknown struct {
+			Name string `json:"name" validate:"notExist"`
+		}
+		assert.Error(t, ValidateStruct(unknown{Name: "test"}))
+	})
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("prefix", func(value interface{}, param string) error {
+		if s, _ := value.(string); len(s) < len(param) || s[:len(param)] != param {
+			return errors.New("must start with " + param)
+		}
+		return nil
+	})
+
+	type params struct {
+		OrderID string `json:"orderId" validate:"required,prefix=SO"`
+	}
+
+	t.Run("Testcase #1: Positive", func(t *testing.T) {
+		var p params
+		assert.NoError(t, ParseFromQueryParam(url.Values{"orderId": {"SO001"}}, &p))
+	})
+	t.Run("Testcase #2: Negative, validated after ParseFromQueryParam and BindQuery", func(t *testing.T) {
+		var p params
+		err := ParseFromQueryParam(url.Values{"orderId": {"PO001"}}, &p)
+		assert.Equal(t, "orderId: must start with SO", err.Error())
+
+		var empty params
+		err = BindQuery(url.Values{}, &empty)
+		assert.Equal(t, "orderId: is required", err.Error())
+	})
+}